@@ -3,29 +3,40 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/launch01/mission-control/internal/config"
 	"github.com/launch01/mission-control/internal/logging"
 	"github.com/launch01/mission-control/internal/mcp"
 	"github.com/launch01/mission-control/internal/oauth"
+	"github.com/launch01/mission-control/internal/policy"
 	"github.com/launch01/mission-control/internal/storage"
 )
 
 // Agent combines OAuth and MCP functionality
 type Agent struct {
-	cfg         *config.Config
-	mcpClient   *mcp.Client
-	storage     *storage.TokenStorage
-	oauthFlow   *oauth.AuthFlow
+	cfg       *config.Config
+	servers   []*server
+	storage   *storage.TokenStorage
+	oauthFlow *oauth.AuthFlow
+	policy    *policy.Policy
+	confirm   Confirmer
+
+	mu             sync.Mutex
+	resourceRoutes map[string]*server
 }
 
 // NewAgent creates a new agent
 func NewAgent(cfg *config.Config) (*Agent, error) {
-	mcpClient := mcp.NewClient(cfg.MCP.URL, cfg.MCP.AuthMode)
+	servers, err := newServers(cfg.MCP.ServerList())
+	if err != nil {
+		return nil, err
+	}
 
-	store, err := storage.NewTokenStorage()
+	store, err := storage.NewProfileTokenStorage(cfg.Profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token storage: %w", err)
 	}
@@ -35,59 +46,230 @@ func NewAgent(cfg *config.Config) (*Agent, error) {
 		return nil, fmt.Errorf("failed to create OAuth flow: %w", err)
 	}
 
+	var toolPolicy *policy.Policy
+	if cfg.PolicyFile != "" {
+		toolPolicy, err = policy.Load(cfg.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Agent{
-		cfg:       cfg,
-		mcpClient: mcpClient,
-		storage:   store,
-		oauthFlow: authFlow,
+		cfg:            cfg,
+		servers:        servers,
+		storage:        store,
+		oauthFlow:      authFlow,
+		policy:         toolPolicy,
+		resourceRoutes: make(map[string]*server),
 	}, nil
 }
 
+// Close stops any MCP server processes started by the agent
+func (a *Agent) Close() error {
+	for _, srv := range a.servers {
+		srv.client.Close()
+	}
+	return nil
+}
+
 // EnsureAuthenticated ensures we have a valid token
 func (a *Agent) EnsureAuthenticated(ctx context.Context) error {
 	token, err := a.storage.LoadToken()
 	if err != nil {
-		return fmt.Errorf("not authenticated - please run 'mission-control auth login': %w", err)
+		return &AuthError{Kind: ErrNotAuthenticated, Err: err}
 	}
 
-	// Refresh if expired or expiring soon
-	if token.IsExpired() || token.IsExpiringSoon(5*time.Minute) {
+	// Refresh if expired or expiring soon; private app tokens never expire
+	if !token.IsPrivateApp() && (token.IsExpired() || token.IsExpiringSoon(5*time.Minute)) {
 		logging.Info("Token expired or expiring soon, refreshing...")
 		if err := a.oauthFlow.RefreshToken(ctx); err != nil {
-			return fmt.Errorf("failed to refresh token: %w", err)
+			return &AuthError{Kind: ErrRefreshFailed, Err: err}
 		}
 		// Reload token after refresh
 		token, err = a.storage.LoadToken()
 		if err != nil {
-			return fmt.Errorf("failed to reload token: %w", err)
+			return &AuthError{Kind: ErrNotAuthenticated, Err: fmt.Errorf("failed to reload token: %w", err)}
 		}
 	}
 
-	// Set token on MCP client
-	a.mcpClient.SetToken(token.AccessToken)
+	// Set token on every MCP client that uses the HubSpot token
+	for _, srv := range a.servers {
+		if srv.cfg.TokenSource == config.TokenSourceOAuth {
+			srv.client.SetToken(token.AccessToken)
+		}
+	}
 	return nil
 }
 
-// ListTools lists all available MCP tools
+// ListTools lists the tools of every configured MCP server, named "server/tool"
 func (a *Agent) ListTools(ctx context.Context) ([]mcp.Tool, error) {
-	if err := a.EnsureAuthenticated(ctx); err != nil {
-		return nil, err
+	var tools []mcp.Tool
+	var errs []error
+	for _, srv := range a.servers {
+		serverTools, err := a.listServerTools(ctx, srv)
+		if err != nil {
+			// One unreachable server should not hide the tools of the others
+			logging.Error("Failed to list tools from MCP server %s: %v", srv.cfg.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		tools = append(tools, serverTools...)
+	}
+
+	if len(errs) == len(a.servers) {
+		return nil, errors.Join(errs...)
+	}
+
+	return tools, nil
+}
+
+// ListServerTools lists the tools of a single named MCP server
+func (a *Agent) ListServerTools(ctx context.Context, name string) ([]mcp.Tool, error) {
+	srv := a.server(name)
+	if srv == nil {
+		return nil, fmt.Errorf("unknown MCP server %q", name)
 	}
 
-	return a.mcpClient.ListTools(ctx)
+	return a.listServerTools(ctx, srv)
 }
 
-// CallTool calls an MCP tool
+// Servers returns the names of the configured MCP servers, primary first
+func (a *Agent) Servers() []string {
+	names := make([]string, len(a.servers))
+	for i, srv := range a.servers {
+		names[i] = srv.cfg.Name
+	}
+	return names
+}
+
+// CallTool calls an MCP tool. Names of the form "server/tool" are routed to that server;
+// bare names go to the primary HubSpot server. The tool's configured defaults fill in
+// missing arguments, then the tool policy is checked.
 func (a *Agent) CallTool(ctx context.Context, name string, args map[string]interface{}) (json.RawMessage, error) {
-	if err := a.EnsureAuthenticated(ctx); err != nil {
+	args = a.withDefaults(name, args)
+	if err := a.checkPolicy(name, args); err != nil {
+		return nil, err
+	}
+
+	srv, tool := a.route(name)
+	if err := a.authorize(ctx, srv); err != nil {
 		return nil, err
 	}
 
-	return a.mcpClient.CallTool(ctx, name, args)
+	ctx = logging.NewContext(ctx, "tool", tool, "server", srv.cfg.Name)
+	start := time.Now()
+	result, err := srv.client.CallTool(ctx, tool, args)
+	err = checkScopes(err)
+	if err != nil {
+		logging.FromContext(ctx).Debug("Tool call failed", "duration", time.Since(start), "error", err)
+	} else {
+		logging.FromContext(ctx).Debug("Tool call", "duration", time.Since(start))
+	}
+	return result, err
 }
 
-// GetAuthStatus returns the current authentication status
-func (a *Agent) GetAuthStatus() (*AuthStatus, error) {
+// CallBatch calls several MCP tools, batching them into as few requests as each server supports.
+// Results are returned in the same order as calls; per-call failures, including
+// policy denials, are reported in each result.
+func (a *Agent) CallBatch(ctx context.Context, calls []mcp.ToolCall) ([]mcp.BatchResult, error) {
+	results := make([]mcp.BatchResult, len(calls))
+
+	// Group allowed calls by server, remembering each call's original position
+	groups := make(map[*server][]int)
+	var order []*server
+	calls = append([]mcp.ToolCall(nil), calls...)
+	for i, call := range calls {
+		calls[i].Arguments = a.withDefaults(call.Name, call.Arguments)
+		if err := a.checkPolicy(call.Name, calls[i].Arguments); err != nil {
+			results[i] = mcp.BatchResult{Err: err}
+			continue
+		}
+
+		srv, _ := a.route(call.Name)
+		if _, ok := groups[srv]; !ok {
+			order = append(order, srv)
+		}
+		groups[srv] = append(groups[srv], i)
+	}
+
+	for _, srv := range order {
+		indexes := groups[srv]
+		if err := a.authorize(ctx, srv); err != nil {
+			return nil, err
+		}
+
+		serverCalls := make([]mcp.ToolCall, len(indexes))
+		for j, i := range indexes {
+			_, tool := a.route(calls[i].Name)
+			serverCalls[j] = mcp.ToolCall{Name: tool, Arguments: calls[i].Arguments}
+		}
+
+		serverResults, err := srv.client.CallToolBatch(ctx, serverCalls)
+		if err != nil {
+			return nil, fmt.Errorf("batch to MCP server %s failed: %w", srv.cfg.Name, checkScopes(err))
+		}
+		for j, i := range indexes {
+			results[i] = serverResults[j]
+			results[i].Err = checkScopes(results[i].Err)
+		}
+	}
+
+	return results, nil
+}
+
+// withDefaults fills in the configured default arguments of a tool. Defaults are
+// looked up by "server/tool", then by the bare name for the primary server.
+func (a *Agent) withDefaults(name string, args map[string]interface{}) map[string]interface{} {
+	if len(a.cfg.ToolDefaults) == 0 {
+		return args
+	}
+	srv, tool := a.route(name)
+	qualified := srv.cfg.Name + "/" + tool
+	if _, ok := a.cfg.ToolDefaults[qualified]; ok || srv != a.servers[0] {
+		return a.cfg.ToolDefaults.Apply(qualified, args)
+	}
+	return a.cfg.ToolDefaults.Apply(tool, args)
+}
+
+// KeepAlive refreshes the token in the background until ctx is done, ahead of expiry
+// rather than when a call finds it expiring, and pushes each new token to the MCP
+// servers that use it. The returned source reports the token's health; it is nil
+// when no server uses the OAuth token, which then needn't exist.
+func (a *Agent) KeepAlive(ctx context.Context) *oauth.TokenSource {
+	if !a.usesOAuth() {
+		return nil
+	}
+	source := oauth.NewTokenSource(a.oauthFlow, oauth.DefaultRefreshLead)
+	source.Subscribe(func(token *storage.Token) {
+		for _, srv := range a.servers {
+			if srv.cfg.TokenSource == config.TokenSourceOAuth {
+				srv.client.SetToken(token.AccessToken)
+			}
+		}
+	})
+
+	go func() {
+		if err := source.Run(ctx); err != nil {
+			logging.Error("Token keeper stopped: %v", err)
+		}
+	}()
+	return source
+}
+
+// usesOAuth reports whether any server uses the token managed by mission-control
+func (a *Agent) usesOAuth() bool {
+	for _, srv := range a.servers {
+		if srv.cfg.TokenSource == config.TokenSourceOAuth {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAuthStatus returns the current authentication status, including the
+// portal, user, app and scopes the token belongs to. It works from the metadata
+// stored with the token; refresh forces it to be fetched again.
+func (a *Agent) GetAuthStatus(ctx context.Context, refresh bool) (*AuthStatus, error) {
 	token, err := a.storage.LoadToken()
 	if err != nil {
 		return &AuthStatus{
@@ -97,12 +279,20 @@ func (a *Agent) GetAuthStatus() (*AuthStatus, error) {
 	}
 
 	status := &AuthStatus{
-		Authenticated: true,
-		ExpiresAt:     token.ExpiresAt,
-		IsExpired:     token.IsExpired(),
+		Authenticated:  true,
+		CredentialType: token.CredentialType(),
+		ExpiresAt:      token.ExpiresAt,
+		IsExpired:      token.IsExpired(),
+		ClientID:       token.ClientID,
+		ServerURL:      token.ServerURL,
+		ObtainedAt:     token.ObtainedAt,
+		RefreshedAt:    token.RefreshedAt,
+		RefreshCount:   token.RefreshCount,
 	}
 
-	if token.IsExpired() {
+	if token.IsPrivateApp() {
+		status.Message = "Authenticated with a private app access token"
+	} else if token.IsExpired() {
 		status.Message = "Token expired - will be refreshed on next use"
 	} else if token.IsExpiringSoon(5 * time.Minute) {
 		status.Message = "Token expiring soon - will be refreshed on next use"
@@ -110,13 +300,57 @@ func (a *Agent) GetAuthStatus() (*AuthStatus, error) {
 		status.Message = "Authenticated"
 	}
 
+	// Token metadata is cached with the token and survives refreshes, so it is
+	// fetched once per login unless refresh is set
+	if !token.IsExpired() && (token.Info == nil || refresh) {
+		info, err := a.oauthFlow.Introspect(ctx, token)
+		if err != nil {
+			status.InfoError = err.Error()
+		} else {
+			cache := func(t *storage.Token) error {
+				t.Info = info
+				t.HubID = info.HubID
+				t.Scopes = info.Scopes
+				return nil
+			}
+			cache(token)
+			if err := a.storage.Update(ctx, cache); err != nil && !errors.Is(err, storage.ErrReadOnly) {
+				logging.Error("Failed to cache token info: %v", err)
+			}
+		}
+	}
+
+	status.Info = token.Info
+	status.HubID = token.HubID
+	status.Scopes = token.Scopes
+	if !token.IsPrivateApp() && len(token.Scopes) > 0 {
+		status.MissingScopes = oauth.MissingScopes(a.cfg.HubSpot.Scopes, token.Scopes)
+	}
+
 	return status, nil
 }
 
 // AuthStatus represents authentication status
 type AuthStatus struct {
-	Authenticated bool
-	ExpiresAt     time.Time
-	IsExpired     bool
-	Message       string
+	Authenticated  bool
+	CredentialType string // storage.TokenTypeOAuth or storage.TokenTypePrivateApp
+	ExpiresAt      time.Time
+	IsExpired      bool
+	Message        string
+
+	// Info describes the portal, user, app and granted scopes; nil if it could not be fetched
+	Info *storage.TokenInfo
+	// InfoError explains why Info is missing
+	InfoError string
+	// MissingScopes lists configured scopes the OAuth token was not granted
+	MissingScopes []string
+
+	// Metadata stored with the token
+	HubID        int64
+	Scopes       []string
+	ClientID     string
+	ServerURL    string
+	ObtainedAt   time.Time
+	RefreshedAt  time.Time
+	RefreshCount int
 }
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/launch01/mission-control/internal/config"
+	"github.com/launch01/mission-control/internal/mcp"
+	"github.com/launch01/mission-control/internal/policy"
+)
+
+// newTestAgent creates an agent for servers that are never contacted
+func newTestAgent(t *testing.T, cfg *config.Config, servers ...config.MCPServerConfig) *Agent {
+	t.Helper()
+	for i := range servers {
+		servers[i].Transport = config.TransportHTTP
+		servers[i].URL = "http://127.0.0.1:1"
+	}
+	srvs, err := newServers(servers)
+	if err != nil {
+		t.Fatalf("newServers() error = %v", err)
+	}
+	return &Agent{cfg: cfg, servers: srvs, resourceRoutes: map[string]*server{}}
+}
+
+func TestKeepAliveWithoutOAuthServers(t *testing.T) {
+	a := newTestAgent(t, &config.Config{},
+		config.MCPServerConfig{Name: "hubspot", TokenSource: "env:HUBSPOT_TOKEN"},
+		config.MCPServerConfig{Name: "local", TokenSource: config.TokenSourceNone},
+	)
+	if source := a.KeepAlive(context.Background()); source != nil {
+		t.Error("KeepAlive() should not keep a token fresh when no server uses it")
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	cfg := &config.Config{ToolDefaults: config.ToolDefaults{
+		"search":         {"limit": 20, "archived": false},
+		"crm/search":     {"limit": 5},
+		"local/search":   {"limit": 1},
+		"hubspot/update": {"dryRun": true},
+	}}
+	a := newTestAgent(t, cfg,
+		config.MCPServerConfig{Name: "hubspot", TokenSource: config.TokenSourceNone},
+		config.MCPServerConfig{Name: "crm", TokenSource: config.TokenSourceNone},
+		config.MCPServerConfig{Name: "other", TokenSource: config.TokenSourceNone},
+	)
+
+	tests := []struct {
+		name string
+		tool string
+		args map[string]interface{}
+		want map[string]interface{}
+	}{
+		{"bare name on the primary server", "search", nil, map[string]interface{}{"limit": 20, "archived": false}},
+		{"qualified name on the primary server", "hubspot/search", nil, map[string]interface{}{"limit": 20, "archived": false}},
+		{"qualified defaults on the primary server", "update", nil, map[string]interface{}{"dryRun": true}},
+		{"other server uses its qualified defaults", "crm/search", nil, map[string]interface{}{"limit": 5}},
+		{"other server ignores bare defaults", "other/search", nil, nil},
+		{"unknown server prefix is a primary tool name", "local/search", nil, map[string]interface{}{"limit": 1}},
+		{"given arguments win", "search", map[string]interface{}{"limit": 3}, map[string]interface{}{"limit": 3, "archived": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.withDefaults(tt.tool, tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withDefaults(%q) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultsCheckedByPolicy(t *testing.T) {
+	p, err := policy.Parse([]byte(`
+default: allow
+rules:
+  - tools: ["hubspot/search"]
+    args:
+      limit:
+        max: 100
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		defaults   config.ToolDefaults
+		args       map[string]interface{}
+		wantDenied bool
+	}{
+		{"defaulted argument breaks a rule", config.ToolDefaults{"search": {"limit": 500}}, nil, true},
+		{"given argument overrides the default", config.ToolDefaults{"search": {"limit": 500}}, map[string]interface{}{"limit": 50}, false},
+		{"defaulted argument within the rule", config.ToolDefaults{"search": {"limit": 50}}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAgent(t, &config.Config{ToolDefaults: tt.defaults},
+				config.MCPServerConfig{Name: "hubspot", TokenSource: config.TokenSourceNone})
+			a.policy = p
+
+			// policy test gives the decision of the real call
+			if decision := a.EvaluatePolicy("search", tt.args); decision.Allowed == tt.wantDenied {
+				t.Errorf("EvaluatePolicy() = %+v, want denied: %v", decision, tt.wantDenied)
+			}
+
+			// Allowed calls go on to the unreachable server and fail there
+			_, err := a.CallTool(context.Background(), "search", tt.args)
+			if denied := errors.Is(err, policy.ErrDenied); denied != tt.wantDenied {
+				t.Errorf("CallTool() error = %v, want denied: %v", err, tt.wantDenied)
+			}
+
+			// A denied call is reported in its result without contacting the server
+			results, err := a.CallBatch(context.Background(), []mcp.ToolCall{{Name: "search", Arguments: tt.args}})
+			if tt.wantDenied && (err != nil || !errors.Is(results[0].Err, policy.ErrDenied)) {
+				t.Errorf("CallBatch() = %v, %v; want the call denied", results, err)
+			}
+			if !tt.wantDenied && err == nil && errors.Is(results[0].Err, policy.ErrDenied) {
+				t.Errorf("CallBatch() result error = %v, want the call allowed", results[0].Err)
+			}
+		})
+	}
+}
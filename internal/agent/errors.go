@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/launch01/mission-control/internal/mcp"
+)
+
+// Sentinel errors for local authentication failures, usable with errors.Is
+var (
+	// ErrNotAuthenticated means no usable token is stored
+	ErrNotAuthenticated = errors.New("not authenticated")
+	// ErrRefreshFailed means the stored token could not be refreshed
+	ErrRefreshFailed = errors.New("token refresh failed")
+	// ErrMissingScopes means HubSpot rejected a call because the token lacks a scope
+	ErrMissingScopes = errors.New("token is missing required scopes")
+)
+
+// AuthError reports an authentication failure that happened before a request was sent.
+// It matches its Kind sentinel and unwraps to the underlying cause.
+type AuthError struct {
+	Kind error
+	Err  error
+}
+
+func (e *AuthError) Error() string {
+	switch e.Kind {
+	case ErrNotAuthenticated:
+		return fmt.Sprintf("not authenticated - please run 'mission-control auth login': %v", e.Err)
+	case ErrRefreshFailed:
+		return fmt.Sprintf("failed to refresh token: %v", e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *AuthError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// ScopeError reports a call rejected because the token lacks one or more scopes.
+// It matches ErrMissingScopes and unwraps to the MCP error.
+type ScopeError struct {
+	Scopes []string
+	Err    error
+}
+
+func (e *ScopeError) Error() string {
+	if len(e.Scopes) == 0 {
+		return fmt.Sprintf("token is missing a required scope - grant it with 'mission-control auth login --add-scopes <scope>': %v", e.Err)
+	}
+	scopes := strings.Join(e.Scopes, ",")
+	return fmt.Sprintf("token is missing required scope %s - run 'mission-control auth login --add-scopes %s'", strings.Join(e.Scopes, ", "), scopes)
+}
+
+func (e *ScopeError) Unwrap() []error {
+	return []error{ErrMissingScopes, e.Err}
+}
+
+// checkScopes wraps err in a ScopeError when it is a missing-scope failure
+func checkScopes(err error) error {
+	if err == nil {
+		return nil
+	}
+	scopes, ok := mcp.MissingScopes(err)
+	if !ok {
+		return err
+	}
+	return &ScopeError{Scopes: scopes, Err: err}
+}
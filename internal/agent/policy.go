@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"github.com/launch01/mission-control/internal/policy"
+)
+
+// Confirmer asks the user whether a tool call that the policy flags for confirmation may proceed
+type Confirmer func(tool string, args map[string]interface{}) bool
+
+// SetConfirmer sets the function used to confirm calls. Without one,
+// calls that require confirmation are refused.
+func (a *Agent) SetConfirmer(confirm Confirmer) {
+	a.confirm = confirm
+}
+
+// Policy returns the active tool policy, or nil if none is configured
+func (a *Agent) Policy() *policy.Policy {
+	return a.policy
+}
+
+// EvaluatePolicy dry-runs a tool call against the policy without calling the tool.
+// Default arguments are filled in first, as for a real call.
+func (a *Agent) EvaluatePolicy(name string, args map[string]interface{}) policy.Decision {
+	if a.policy == nil {
+		return policy.Decision{Allowed: true}
+	}
+	srv, tool := a.route(name)
+	return a.policy.Evaluate(srv.cfg.Name+"/"+tool, a.withDefaults(name, args))
+}
+
+// checkPolicy enforces the policy for a call before it leaves the process
+func (a *Agent) checkPolicy(name string, args map[string]interface{}) error {
+	if a.policy == nil {
+		return nil
+	}
+
+	srv, tool := a.route(name)
+	qualified := srv.cfg.Name + "/" + tool
+
+	decision := a.policy.Evaluate(qualified, args)
+	if !decision.Allowed {
+		return &policy.DeniedError{Tool: qualified, Reasons: decision.Reasons}
+	}
+
+	if decision.Confirm {
+		if a.confirm == nil {
+			return &policy.DeniedError{Tool: qualified, Reasons: []string{policy.ErrConfirmationRequired.Error()}}
+		}
+		if !a.confirm(qualified, args) {
+			return &policy.DeniedError{Tool: qualified, Reasons: []string{"not confirmed"}}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/launch01/mission-control/internal/logging"
+	"github.com/launch01/mission-control/internal/mcp"
+)
+
+// ListResources lists the resources of every configured MCP server.
+// Servers that do not implement resources are skipped.
+func (a *Agent) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	var resources []mcp.Resource
+	err := a.eachServer(ctx, "resources", func(srv *server) error {
+		serverResources, err := srv.client.ListResources(ctx)
+		if err != nil {
+			return err
+		}
+
+		a.mu.Lock()
+		for i := range serverResources {
+			serverResources[i].Server = srv.cfg.Name
+			a.resourceRoutes[serverResources[i].URI] = srv
+		}
+		a.mu.Unlock()
+
+		resources = append(resources, serverResources...)
+		return nil
+	})
+	return resources, err
+}
+
+// ListResourceTemplates lists the resource templates of every configured MCP server
+func (a *Agent) ListResourceTemplates(ctx context.Context) ([]mcp.ResourceTemplate, error) {
+	var templates []mcp.ResourceTemplate
+	err := a.eachServer(ctx, "resource templates", func(srv *server) error {
+		serverTemplates, err := srv.client.ListResourceTemplates(ctx)
+		if err != nil {
+			return err
+		}
+		templates = append(templates, serverTemplates...)
+		return nil
+	})
+	return templates, err
+}
+
+// ReadResource reads a resource from the server that listed it, or from the primary server
+func (a *Agent) ReadResource(ctx context.Context, uri string) (json.RawMessage, error) {
+	a.mu.Lock()
+	srv, ok := a.resourceRoutes[uri]
+	a.mu.Unlock()
+	if !ok {
+		srv = a.servers[0]
+	}
+
+	if err := a.authorize(ctx, srv); err != nil {
+		return nil, err
+	}
+
+	return srv.client.ReadResource(ctx, uri)
+}
+
+// ListPrompts lists the prompts of every configured MCP server, named "server/prompt"
+func (a *Agent) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	var prompts []mcp.Prompt
+	err := a.eachServer(ctx, "prompts", func(srv *server) error {
+		serverPrompts, err := srv.client.ListPrompts(ctx)
+		if err != nil {
+			return err
+		}
+		for i := range serverPrompts {
+			serverPrompts[i].Name = srv.cfg.Name + "/" + serverPrompts[i].Name
+		}
+		prompts = append(prompts, serverPrompts...)
+		return nil
+	})
+	return prompts, err
+}
+
+// GetPrompt renders a prompt, routing "server/prompt" names like CallTool does
+func (a *Agent) GetPrompt(ctx context.Context, name string, args map[string]string) (json.RawMessage, error) {
+	srv, prompt := a.route(name)
+	if err := a.authorize(ctx, srv); err != nil {
+		return nil, err
+	}
+
+	return srv.client.GetPrompt(ctx, prompt, args)
+}
+
+// eachServer runs fn against every authorized server. Servers that don't implement
+// the capability are skipped; other failures are logged and only returned if every server failed.
+func (a *Agent) eachServer(ctx context.Context, what string, fn func(srv *server) error) error {
+	var errs []error
+	for _, srv := range a.servers {
+		if err := a.authorize(ctx, srv); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		err := fn(srv)
+		if errors.Is(err, mcp.ErrMethodNotFound) {
+			continue
+		}
+		if err != nil {
+			logging.Error("Failed to list %s from MCP server %s: %v", what, srv.cfg.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", srv.cfg.Name, err))
+		}
+	}
+
+	if len(errs) > 0 && len(errs) == len(a.servers) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
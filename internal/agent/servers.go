@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/launch01/mission-control/internal/config"
+	"github.com/launch01/mission-control/internal/mcp"
+)
+
+// server pairs a configured MCP server with its client
+type server struct {
+	cfg    config.MCPServerConfig
+	client *mcp.Client
+}
+
+// newServers creates a client for each configured MCP server
+func newServers(configs []config.MCPServerConfig) ([]*server, error) {
+	servers := make([]*server, 0, len(configs))
+	for _, sc := range configs {
+		var transport mcp.Transport
+		switch sc.Transport {
+		case config.TransportStdio:
+			transport = mcp.NewStdioTransport(mcp.StdioOptions{
+				Command:  sc.Command,
+				Args:     sc.Args,
+				TokenEnv: sc.TokenEnv,
+			})
+		case config.TransportHTTP:
+			transport = mcp.NewHTTPTransport(sc.URL, sc.AuthMode)
+		default:
+			return nil, fmt.Errorf("MCP server %q: unknown transport %q", sc.Name, sc.Transport)
+		}
+
+		client := mcp.NewClientWithTransport(transport)
+		if strings.HasPrefix(sc.TokenSource, config.TokenSourceEnvPrefix) {
+			client.SetToken(os.Getenv(strings.TrimPrefix(sc.TokenSource, config.TokenSourceEnvPrefix)))
+		}
+
+		servers = append(servers, &server{cfg: sc, client: client})
+	}
+	return servers, nil
+}
+
+// server returns the named server, or nil if none is configured
+func (a *Agent) server(name string) *server {
+	for _, srv := range a.servers {
+		if srv.cfg.Name == name {
+			return srv
+		}
+	}
+	return nil
+}
+
+// route resolves a possibly namespaced tool name to its server and the server-local tool name
+func (a *Agent) route(name string) (*server, string) {
+	if prefix, tool, ok := strings.Cut(name, "/"); ok {
+		if srv := a.server(prefix); srv != nil {
+			return srv, tool
+		}
+	}
+	return a.servers[0], name
+}
+
+// authorize makes sure the server's client carries a valid token before a call
+func (a *Agent) authorize(ctx context.Context, srv *server) error {
+	if srv.cfg.TokenSource != config.TokenSourceOAuth {
+		return nil
+	}
+	return a.EnsureAuthenticated(ctx)
+}
+
+// listServerTools lists one server's tools, prefixing their names with the server name
+func (a *Agent) listServerTools(ctx context.Context, srv *server) ([]mcp.Tool, error) {
+	if err := a.authorize(ctx, srv); err != nil {
+		return nil, err
+	}
+
+	tools, err := srv.client.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tools {
+		tools[i].Server = srv.cfg.Name
+		tools[i].Name = srv.cfg.Name + "/" + tools[i].Name
+	}
+	return tools, nil
+}
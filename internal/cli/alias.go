@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Tool call aliases defined in the config files",
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List aliases and per-tool default arguments",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(cfg.Aliases) == 0 && len(cfg.ToolDefaults) == 0 {
+			fmt.Println("No aliases or tool defaults configured - add them under aliases: and defaults: in a config file")
+			return nil
+		}
+
+		names := make([]string, 0, len(cfg.Aliases))
+		for name := range cfg.Aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			fmt.Println("Aliases:")
+		}
+		for _, name := range names {
+			alias := cfg.Aliases[name]
+			fmt.Printf("  %-20s %s\n", name, alias.Tool)
+			if alias.Description != "" {
+				fmt.Printf("  %-20s %s\n", "", alias.Description)
+			}
+			if params := alias.Params(); len(params) > 0 {
+				fmt.Printf("  %-20s flags: --%s\n", "", strings.Join(params, ", --"))
+			}
+			preset, _ := json.Marshal(alias.Args)
+			fmt.Printf("  %-20s args: %s\n", "", preset)
+			fmt.Printf("  %-20s from %s\n", "", alias.Source)
+		}
+
+		tools := make([]string, 0, len(cfg.ToolDefaults))
+		for tool := range cfg.ToolDefaults {
+			tools = append(tools, tool)
+		}
+		sort.Strings(tools)
+		if len(tools) > 0 {
+			fmt.Println("Tool defaults:")
+		}
+		for _, tool := range tools {
+			defaults, _ := json.Marshal(cfg.ToolDefaults[tool])
+			fmt.Printf("  %-20s %s\n", tool, defaults)
+		}
+		return nil
+	},
+}
+
+var aliasRunCmd = &cobra.Command{
+	Use:   "x <alias> [--flag value]...",
+	Short: "Call a tool through an alias",
+	Long: `Call the tool of an alias defined in a config file, with its preset arguments.
+Flags fill the alias's {{.Args.<flag>}} placeholders; any other flag sets the tool
+argument of the same name, decoded as JSON when possible. Global flags such as
+--profile and --yes keep their meaning and can't be alias flags.`,
+	Example: `  mission-control x open-deals --owner me
+  mission-control x open-deals --owner me --limit 5`,
+	Args: cobra.MinimumNArgs(1),
+	// Flags after the alias name belong to the alias
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		args, err := setGlobalFlags(args)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+			return cmd.Help()
+		}
+		// The config was loaded before the global flags were known
+		if cfg, err = loadConfig(profile); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if err := configureLogging(cfg); err != nil {
+			return err
+		}
+
+		alias, ok := cfg.Aliases[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown alias %q - see 'mission-control alias list'", args[0])
+		}
+		flags, err := parseAliasFlags(args[1:])
+		if err != nil {
+			return fmt.Errorf("alias %s: %w", alias.Name, err)
+		}
+		inputArgs, err := alias.Render(flags)
+		if err != nil {
+			return err
+		}
+
+		ag, err := newAgent()
+		if err != nil {
+			return fmt.Errorf("failed to create agent: %w", err)
+		}
+		defer ag.Close()
+
+		result, err := ag.CallTool(context.Background(), alias.Tool, inputArgs)
+		if err != nil {
+			return fmt.Errorf("tool call failed: %w", err)
+		}
+
+		var formatted interface{}
+		json.Unmarshal(result, &formatted)
+		output, _ := json.MarshalIndent(formatted, "", "  ")
+		fmt.Println(string(output))
+		return nil
+	},
+}
+
+// setGlobalFlags sets the global flags among args, which cobra leaves unparsed when
+// flag parsing is disabled, and returns the other arguments
+func setGlobalFlags(args []string) ([]string, error) {
+	flags := RootCmd.PersistentFlags()
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		flag := flags.Lookup(name)
+		if !strings.HasPrefix(arg, "--") {
+			flag = nil
+			if strings.HasPrefix(arg, "-") && len(name) == 1 {
+				flag = flags.ShorthandLookup(name)
+			}
+		}
+		if flag == nil {
+			rest = append(rest, arg)
+			continue
+		}
+
+		if !hasValue {
+			switch {
+			case flag.NoOptDefVal != "":
+				value = flag.NoOptDefVal
+			case i+1 < len(args):
+				value = args[i+1]
+				i++
+			default:
+				return nil, fmt.Errorf("flag --%s needs an argument", flag.Name)
+			}
+		}
+		if err := flags.Set(flag.Name, value); err != nil {
+			return nil, fmt.Errorf("invalid value for --%s: %w", flag.Name, err)
+		}
+	}
+	return rest, nil
+}
+
+// parseAliasFlags parses --name value and --name=value pairs; a flag followed by
+// another flag or nothing is "true"
+func parseAliasFlags(args []string) (map[string]string, error) {
+	flags := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") || len(arg) == 2 {
+			return nil, fmt.Errorf("unexpected argument %q - alias arguments are given as --name value", arg)
+		}
+
+		name, value, ok := strings.Cut(arg[2:], "=")
+		if !ok {
+			value = "true"
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+				value = args[i+1]
+				i++
+			}
+		}
+		flags[name] = value
+	}
+	return flags, nil
+}
+
+func init() {
+	RootCmd.AddCommand(aliasCmd)
+	RootCmd.AddCommand(aliasRunCmd)
+	aliasCmd.AddCommand(aliasListCmd)
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetGlobalFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantRest    []string
+		wantProfile string
+		wantYes     bool
+		wantErr     bool
+	}{
+		{"no global flags", []string{"open-deals", "--owner", "me"}, []string{"open-deals", "--owner", "me"}, "", false, false},
+		{"profile after the alias", []string{"open-deals", "--profile", "sandbox", "--owner", "me"}, []string{"open-deals", "--owner", "me"}, "sandbox", false, false},
+		{"profile with =", []string{"--profile=sandbox", "open-deals"}, []string{"open-deals"}, "sandbox", false, false},
+		{"yes shorthand", []string{"open-deals", "-y", "--owner", "me"}, []string{"open-deals", "--owner", "me"}, "", true, false},
+		{"yes long form", []string{"open-deals", "--yes"}, []string{"open-deals"}, "", true, false},
+		{"single dash long name is an alias argument", []string{"open-deals", "-profile", "x"}, []string{"open-deals", "-profile", "x"}, "", false, false},
+		{"profile without a value", []string{"open-deals", "--profile"}, nil, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := RootCmd.PersistentFlags()
+			t.Cleanup(func() {
+				profile, assumeYes = "", false
+				for _, name := range []string{"profile", "yes"} {
+					flags.Lookup(name).Changed = false
+				}
+			})
+
+			rest, err := setGlobalFlags(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setGlobalFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(rest, tt.wantRest) {
+				t.Errorf("rest = %q, want %q", rest, tt.wantRest)
+			}
+			if profile != tt.wantProfile || assumeYes != tt.wantYes {
+				t.Errorf("profile = %q, yes = %v; want %q, %v", profile, assumeYes, tt.wantProfile, tt.wantYes)
+			}
+		})
+	}
+}
+
+func TestParseAliasFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"name value", []string{"--owner", "me"}, map[string]string{"owner": "me"}, false},
+		{"name=value", []string{"--owner=me", "--limit=5"}, map[string]string{"owner": "me", "limit": "5"}, false},
+		{"value with =", []string{"--query=a=b"}, map[string]string{"query": "a=b"}, false},
+		{"flag without a value before another flag", []string{"--archived", "--owner", "me"}, map[string]string{"archived": "true", "owner": "me"}, false},
+		{"trailing flag without a value", []string{"--owner", "me", "--archived"}, map[string]string{"owner": "me", "archived": "true"}, false},
+		{"empty =value", []string{"--owner="}, map[string]string{"owner": ""}, false},
+		{"positional argument", []string{"me"}, nil, true},
+		{"bare --", []string{"--"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAliasFlags(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAliasFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAliasFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
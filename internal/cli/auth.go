@@ -1,15 +1,38 @@
 package cli
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/launch01/mission-control/internal/agent"
+	"github.com/launch01/mission-control/internal/config"
 	"github.com/launch01/mission-control/internal/logging"
 	"github.com/launch01/mission-control/internal/oauth"
+	"github.com/launch01/mission-control/internal/storage"
 	"github.com/spf13/cobra"
 )
 
+var (
+	noBrowser     bool
+	listenAddr    string
+	logoutAll     bool
+	localOnly     bool
+	statusRefresh bool
+	addScopes     []string
+	loginServer   string
+	loginClientID string
+	keepaliveLead time.Duration
+)
+
 var authCmd = &cobra.Command{
 	Use:   "auth",
 	Short: "Authentication commands",
@@ -17,24 +40,134 @@ var authCmd = &cobra.Command{
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
-	Short: "Login with HubSpot OAuth",
+	Short: "Login with HubSpot OAuth or an MCP server's authorization server",
+	Example: `  mission-control auth login
+  mission-control auth login --no-browser                 # SSH or container: paste the redirect URL
+  mission-control auth login --no-browser --listen 9400   # Also accept the callback on a forwarded port
+  mission-control auth login --add-scopes crm.objects.tickets.read
+  mission-control auth login --server https://mcp.example.com/mcp   # Discover, register and log in`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		if loginServer != "" {
+			if err := configureServerLogin(ctx, loginServer); err != nil {
+				return err
+			}
+		}
+
+		if len(addScopes) > 0 {
+			cfg.HubSpot.Scopes = oauth.MergeScopes(cfg.HubSpot.Scopes, addScopes...)
+			logging.Info("Requesting scopes: %s", cfg.HubSpot.Scopes)
+		}
+
 		flow, err := oauth.NewAuthFlow(cfg)
 		if err != nil {
 			return fmt.Errorf("failed to create auth flow: %w", err)
 		}
 
 		logging.Info("Starting OAuth login flow...")
-		ctx := context.Background()
-		if err := flow.Login(ctx); err != nil {
+		opts := oauth.LoginOptions{
+			NoBrowser:  noBrowser,
+			ListenAddr: listenAddr,
+			Input:      cmd.InOrStdin(),
+			Output:     cmd.ErrOrStderr(),
+		}
+		if err := flow.LoginWithOptions(ctx, opts); err != nil {
 			return fmt.Errorf("login failed: %w", err)
 		}
 
-		logging.Info("Successfully authenticated!")
+		if err := rememberProfile(); err != nil {
+			return err
+		}
+
+		logging.Info("Successfully authenticated profile %s!", cfg.Profile)
+		if loginServer != "" && profile == "" {
+			logging.Info("Use it with 'mission-control --profile %s tools list' or 'mission-control auth switch %s'", cfg.Profile, cfg.Profile)
+		}
 		return nil
 	},
 }
 
+// configureServerLogin points cfg at the authorization server protecting an MCP server,
+// registering a client unless one is given or the profile already holds one for the
+// server. Unless a profile is given with --profile, MISSION_CONTROL_PROFILE or a config
+// file, the server's host names the profile.
+func configureServerLogin(ctx context.Context, serverURL string) error {
+	logging.Info("Discovering the authorization server for %s...", serverURL)
+	discovery, err := oauth.Discover(ctx, serverURL)
+	if err != nil {
+		return fmt.Errorf("authorization discovery failed: %w", err)
+	}
+
+	sources, err := config.LoadSources(configFlags(profile))
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if origin := sources.Lookup("profile", "").Origin; origin == config.OriginDefault || origin == config.OriginAuthSwitch {
+		name, err := profileNameForURL(serverURL)
+		if err != nil {
+			return err
+		}
+		if cfg, err = loadConfig(name); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+
+	provider := discovery.Provider()
+	scopes := strings.Join(discovery.Scopes, " ")
+	clientID, clientSecret, clientAuth := loginClientID, "", provider.ClientAuth
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	if existing := profiles.Profiles[cfg.Profile]; existing != nil && existing.OAuthResource == discovery.Resource {
+		scopes = oauth.MergeScopes(scopes, existing.Scopes)
+		if clientID == "" && existing.OAuthTokenURL == provider.TokenURL {
+			clientID, clientSecret, clientAuth = existing.ClientID, existing.ClientSecret, existing.OAuthClientAuth
+		}
+	}
+
+	if clientID == "" {
+		logging.Info("Registering mission-control with %s...", provider.Name)
+		reg, err := discovery.Register(ctx, cfg.HubSpot.RedirectURI)
+		if err != nil {
+			return err
+		}
+		clientID, clientSecret, clientAuth = reg.ClientID, reg.ClientSecret, reg.ClientAuth()
+	}
+
+	cfg.HubSpot.ClientID = clientID
+	cfg.HubSpot.ClientSecret = clientSecret
+	cfg.HubSpot.Scopes = scopes
+	cfg.MCP.URL = serverURL
+	cfg.OAuth = config.OAuthConfig{
+		Provider:   oauth.ProviderCustom,
+		AuthURL:    provider.AuthURL,
+		TokenURL:   provider.TokenURL,
+		RevokeURL:  provider.RevokeURL,
+		ClientAuth: clientAuth,
+		Resource:   provider.Resource,
+	}
+	return nil
+}
+
+// profileNameForURL derives a profile name from a server URL's host and port
+func profileNameForURL(serverURL string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid MCP server URL %q", serverURL)
+	}
+
+	name := u.Hostname()
+	if port := u.Port(); port != "" {
+		name += "-" + port
+	}
+	if err := storage.ValidateProfileName(name); err != nil {
+		return "", fmt.Errorf("can't derive a profile name from %s - use --profile: %w", serverURL, err)
+	}
+	return name, nil
+}
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show authentication status",
@@ -43,8 +176,9 @@ var statusCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to create agent: %w", err)
 		}
+		defer ag.Close()
 
-		status, err := ag.GetAuthStatus()
+		status, err := ag.GetAuthStatus(context.Background(), statusRefresh)
 		if err != nil {
 			return fmt.Errorf("failed to get status: %w", err)
 		}
@@ -56,18 +190,414 @@ var statusCmd = &cobra.Command{
 		}
 
 		fmt.Println("Status: Authenticated")
+		fmt.Printf("Profile: %s\n", cfg.Profile)
 		fmt.Printf("Message: %s\n", status.Message)
-		fmt.Printf("Token expires at: %s\n", status.ExpiresAt.Format("2006-01-02 15:04:05"))
-		if status.IsExpired {
-			fmt.Println("Note: Token is expired and will be refreshed on next use")
+		if status.CredentialType == storage.TokenTypePrivateApp {
+			fmt.Println("Credential type: Private app access token")
+			fmt.Println("Token expires at: never")
+		} else {
+			fmt.Println("Credential type: OAuth")
+			fmt.Printf("Token expires at: %s\n", status.ExpiresAt.Format("2006-01-02 15:04:05"))
+			if status.IsExpired {
+				fmt.Println("Note: Token is expired and will be refreshed on next use")
+			}
+		}
+
+		if status.InfoError != "" {
+			fmt.Printf("Token details unavailable: %s\n", status.InfoError)
+		}
+		if info := status.Info; info != nil {
+			if info.HubDomain != "" {
+				fmt.Printf("Portal: %d (%s)\n", info.HubID, info.HubDomain)
+			} else {
+				fmt.Printf("Portal: %d\n", info.HubID)
+			}
+			if info.User != "" {
+				fmt.Printf("User: %s\n", info.User)
+			} else if info.UserID != 0 {
+				fmt.Printf("User ID: %d\n", info.UserID)
+			}
+			fmt.Printf("App ID: %d\n", info.AppID)
+		} else if status.HubID != 0 {
+			fmt.Printf("Portal: %d\n", status.HubID)
+		}
+		if len(status.Scopes) > 0 {
+			fmt.Printf("Scopes: %s\n", strings.Join(status.Scopes, " "))
+		}
+		if status.ClientID != "" {
+			fmt.Printf("Client ID: %s\n", status.ClientID)
+		}
+		if status.ServerURL != "" {
+			fmt.Printf("MCP server: %s\n", status.ServerURL)
+		}
+		if !status.ObtainedAt.IsZero() {
+			fmt.Printf("Obtained at: %s\n", status.ObtainedAt.Format("2006-01-02 15:04:05"))
+		}
+		if !status.RefreshedAt.IsZero() {
+			fmt.Printf("Last refreshed: %s (%d refreshes)\n", status.RefreshedAt.Format("2006-01-02 15:04:05"), status.RefreshCount)
+		}
+		if len(status.MissingScopes) > 0 {
+			fmt.Printf("Warning: token is missing configured scopes: %s\n", strings.Join(status.MissingScopes, " "))
+			fmt.Println("Run 'mission-control auth login' to grant them")
+		}
+
+		return nil
+	},
+}
+
+var setTokenCmd = &cobra.Command{
+	Use:   "set-token",
+	Short: "Store a HubSpot private app access token read from stdin",
+	Long: `Store a HubSpot private app access token instead of logging in with OAuth.
+The token is read from stdin so it never appears in shell history or process listings.
+Private app tokens do not expire and are never refreshed.`,
+	Example: `  mission-control auth set-token < token.txt
+  pbpaste | mission-control auth set-token`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprintln(os.Stderr, "Paste the private app access token and press Enter:")
+		}
+
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read token from stdin: %w", err)
+		}
+
+		accessToken := strings.TrimSpace(line)
+		if accessToken == "" {
+			return fmt.Errorf("no token provided on stdin")
+		}
+		if !strings.HasPrefix(accessToken, "pat-") {
+			logging.Error("Warning: token does not look like a HubSpot private app token (expected a pat- prefix)")
+		}
+
+		store, err := storage.NewProfileTokenStorage(cfg.Profile)
+		if err != nil {
+			return fmt.Errorf("failed to create token storage: %w", err)
+		}
+
+		token := &storage.Token{
+			AccessToken: accessToken,
+			Type:        storage.TokenTypePrivateApp,
+			ServerURL:   cfg.MCP.URL,
+			ObtainedAt:  time.Now(),
+		}
+		if err := store.SaveToken(token); err != nil {
+			return fmt.Errorf("failed to save token: %w", err)
+		}
+
+		if err := rememberProfile(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Private app token %s stored for profile %s in %s\n", logging.RedactSensitive(accessToken), cfg.Profile, store.Location())
+		return nil
+	},
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Revoke and remove the stored token",
+	Example: `  mission-control auth logout
+  mission-control auth logout --local-only   # Offline: remove without revoking
+  mission-control auth logout --all          # Wipe every stored token`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if logoutAll {
+			return logoutAllProfiles()
+		}
+
+		store, err := storage.NewProfileTokenStorage(cfg.Profile)
+		if err != nil {
+			return fmt.Errorf("failed to create token storage: %w", err)
+		}
+
+		token, err := store.LoadToken()
+		if err != nil {
+			fmt.Println("Not logged in - no stored token found")
+			return nil
+		}
+
+		// Revoke first so a failure leaves the token in place to retry
+		if !localOnly {
+			revoked, err := revokeToken(cfg, token)
+			if err != nil {
+				return fmt.Errorf("%w (use --local-only to remove the token without revoking it)", err)
+			}
+			if revoked {
+				fmt.Println("Revoked refresh token with HubSpot")
+			}
+		}
+
+		if err := store.DeleteToken(); err != nil {
+			return fmt.Errorf("failed to remove token: %w", err)
+		}
+		fmt.Printf("Removed token for profile %s from %s\n", cfg.Profile, store.Location())
+
+		return nil
+	},
+}
+
+// logoutAllProfiles revokes the token of every known profile, unless --local-only is
+// set, then removes every stored token and profile. Tokens are only removed once all
+// of them are revoked, so none is left valid at HubSpot with no way to revoke it.
+func logoutAllProfiles() error {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	names, err := knownProfiles(profiles)
+	if err != nil {
+		return err
+	}
+
+	if !localOnly {
+		var failed []string
+		for _, name := range names {
+			revoked, err := revokeProfileToken(name)
+			if err != nil {
+				fmt.Printf("Failed to revoke the token of profile %s: %v\n", name, err)
+				failed = append(failed, name)
+			} else if revoked {
+				fmt.Printf("Revoked refresh token of profile %s with HubSpot\n", name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to revoke the tokens of profile(s) %s - no token was removed (use --local-only to remove them without revoking)", strings.Join(failed, ", "))
+		}
+	}
+
+	removed, err := storage.DeleteAll(names)
+	for _, location := range removed {
+		fmt.Printf("Removed token from %s\n", location)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove tokens: %w", err)
+	}
+	if len(removed) == 0 {
+		fmt.Println("No stored tokens found")
+	}
+
+	profiles.Profiles = map[string]*config.Profile{}
+	profiles.Current = ""
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d profile(s)\n", len(names))
+	return nil
+}
+
+// revokeProfileToken revokes the refresh token stored for a profile with that profile's
+// settings, reporting whether there was one to revoke
+func revokeProfileToken(name string) (bool, error) {
+	store, err := storage.NewProfileTokenStorage(name)
+	if err != nil {
+		return false, fmt.Errorf("failed to create token storage: %w", err)
+	}
+	token, err := store.LoadToken()
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	profileCfg := cfg
+	if name != cfg.Profile {
+		if profileCfg, err = loadConfig(name); err != nil {
+			return false, fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+	return revokeToken(profileCfg, token)
+}
+
+// revokeToken revokes a token's refresh token, reporting whether it had one. Private
+// app tokens can't be revoked from the CLI.
+func revokeToken(profileCfg *config.Config, token *storage.Token) (bool, error) {
+	if token.IsPrivateApp() {
+		fmt.Printf("Private app tokens cannot be revoked from the CLI - rotate the token of profile %s in HubSpot if it was exposed\n", profileCfg.Profile)
+		return false, nil
+	}
+	if token.RefreshToken == "" {
+		return false, nil
+	}
+
+	flow, err := oauth.NewAuthFlow(profileCfg)
+	if err != nil {
+		return false, fmt.Errorf("failed to create auth flow: %w", err)
+	}
+	if err := flow.RevokeToken(context.Background(), token.RefreshToken); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+var keepaliveCmd = &cobra.Command{
+	Use:   "keepalive",
+	Short: "Keep the stored token fresh until interrupted",
+	Long: `Keepalive refreshes the stored token ahead of expiry and keeps running until
+interrupted, so long-running jobs and other mission-control processes always find a
+valid token. Failed refreshes are retried with exponential backoff.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flow, err := oauth.NewAuthFlow(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create auth flow: %w", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		source := oauth.NewTokenSource(flow, keepaliveLead)
+		source.Subscribe(func(token *storage.Token) {
+			if token.IsPrivateApp() {
+				logging.Info("Using a private app token for profile %s; it never expires", cfg.Profile)
+				return
+			}
+			logging.Info("Token for profile %s valid until %s", cfg.Profile, token.ExpiresAt.Format("2006-01-02 15:04:05"))
+		})
+
+		logging.Info("Keeping the token fresh, refreshing %s before expiry (Ctrl-C to stop)", keepaliveLead)
+		return source.Run(ctx)
+	},
+}
+
+var listProfilesCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles and their tokens",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := config.LoadProfiles()
+		if err != nil {
+			return err
+		}
+		names, err := knownProfiles(profiles)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			marker := " "
+			if name == cfg.Profile {
+				marker = "*"
+			}
+
+			state := "no token"
+			if store, err := storage.NewProfileTokenStorage(name); err == nil {
+				if token, err := store.LoadToken(); err == nil {
+					switch {
+					case token.IsPrivateApp():
+						state = "private app token"
+					case token.IsExpired():
+						state = "OAuth, expired (refreshes on next use)"
+					default:
+						state = "OAuth, expires " + token.ExpiresAt.Format("2006-01-02 15:04:05")
+					}
+					if token.HubID != 0 {
+						state += fmt.Sprintf(", portal %d", token.HubID)
+					}
+				}
+			}
+
+			fmt.Printf("%s %-20s %s\n", marker, name, state)
 		}
 
 		return nil
 	},
 }
 
+var switchProfileCmd = &cobra.Command{
+	Use:   "switch <profile>",
+	Short: "Select the profile used by default",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		profiles, err := config.LoadProfiles()
+		if err != nil {
+			return err
+		}
+		names, err := knownProfiles(profiles)
+		if err != nil {
+			return err
+		}
+
+		known := false
+		for _, n := range names {
+			if n == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown profile %q - create it with 'mission-control auth login --profile %s'", name, name)
+		}
+
+		profiles.Current = name
+		if err := profiles.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Switched to profile %s\n", name)
+		return nil
+	},
+}
+
+// rememberProfile records the active portal settings under the current profile
+func rememberProfile() error {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+
+	sources, err := config.LoadSources(configFlags(cfg.Profile))
+	if err != nil {
+		return err
+	}
+
+	profiles.Remember(cfg, sources)
+	if err := profiles.Save(); err != nil {
+		return fmt.Errorf("failed to save profile %s: %w", cfg.Profile, err)
+	}
+	return nil
+}
+
+// knownProfiles returns every profile with stored settings or a token file, plus the active and default profiles
+func knownProfiles(profiles *config.ProfileStore) ([]string, error) {
+	fileProfiles, err := storage.ListFileProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, group := range [][]string{{config.DefaultProfile, cfg.Profile}, profiles.Names(), fileProfiles} {
+		for _, name := range group {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func init() {
 	RootCmd.AddCommand(authCmd)
 	authCmd.AddCommand(loginCmd)
 	authCmd.AddCommand(statusCmd)
+	authCmd.AddCommand(logoutCmd)
+	authCmd.AddCommand(setTokenCmd)
+	authCmd.AddCommand(keepaliveCmd)
+	authCmd.AddCommand(listProfilesCmd)
+	authCmd.AddCommand(switchProfileCmd)
+
+	loginCmd.Flags().BoolVar(&noBrowser, "no-browser", false, "Print the authorization URL and read the redirect URL or code from stdin")
+	loginCmd.Flags().StringVar(&loginServer, "server", "", "Log in to this MCP server, discovering its authorization server and registering a client")
+	loginCmd.Flags().StringVar(&loginClientID, "client-id", "", "Use this client ID with --server instead of registering one")
+	loginCmd.Flags().StringSliceVar(&addScopes, "add-scopes", nil, "Request these scopes in addition to the profile's current ones")
+	loginCmd.Flags().StringVar(&listenAddr, "listen", "", "Bind the callback server to this address or port instead of the redirect URI's")
+
+	keepaliveCmd.Flags().DurationVar(&keepaliveLead, "lead", oauth.DefaultRefreshLead, "Refresh this long before the token expires")
+	statusCmd.Flags().BoolVar(&statusRefresh, "refresh", false, "Fetch the token details from HubSpot instead of using the cached copy")
+	logoutCmd.Flags().BoolVar(&logoutAll, "all", false, "Revoke and remove every profile's token, from the keyring and the fallback file")
+	logoutCmd.Flags().BoolVar(&localOnly, "local-only", false, "Remove the token without revoking it with HubSpot")
 }
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/launch01/mission-control/internal/storage"
+)
+
+func TestLogoutAllRevokesEveryProfile(t *testing.T) {
+	var mu sync.Mutex
+	var revoked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := path.Base(r.URL.Path)
+		mu.Lock()
+		revoked = append(revoked, token)
+		mu.Unlock()
+		if token == "beta-refresh" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(storage.CredentialBackendEnv, storage.BackendFile)
+	t.Setenv(storage.PassphraseEnv, "")
+	t.Setenv("HUBSPOT_API_URL", server.URL)
+	t.Setenv("HUBSPOT_CLIENT_ID", "client-123")
+
+	for _, name := range []string{"alpha", "beta"} {
+		store, err := storage.NewProfileTokenStorage(name)
+		if err != nil {
+			t.Fatalf("NewProfileTokenStorage() error = %v", err)
+		}
+		if err := store.SaveToken(&storage.Token{AccessToken: name + "-access", RefreshToken: name + "-refresh"}); err != nil {
+			t.Fatalf("SaveToken() error = %v", err)
+		}
+	}
+
+	previous := cfg
+	t.Cleanup(func() { cfg = previous })
+	var err error
+	if cfg, err = loadConfig("alpha"); err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+
+	err = logoutAllProfiles()
+	if err == nil || !strings.Contains(err.Error(), "beta") || strings.Contains(err.Error(), "alpha") {
+		t.Errorf("logoutAllProfiles() error = %v, want the failure of profile beta", err)
+	}
+	if len(revoked) != 2 {
+		t.Errorf("revoked %v, want the tokens of both profiles", revoked)
+	}
+
+	// A failed revocation leaves every token in place to retry
+	for _, name := range []string{"alpha", "beta"} {
+		store, _ := storage.NewProfileTokenStorage(name)
+		if _, err := store.LoadToken(); err != nil {
+			t.Errorf("token of profile %s was removed: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/launch01/mission-control/internal/config"
+	"github.com/launch01/mission-control/internal/storage"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	exportOut       string
+	exportExpiresIn time.Duration
+	importForce     bool
+	passphraseFile  string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the profile's token and settings to an encrypted bundle",
+	Long: `Export the profile's token, saved settings and token metadata to a bundle encrypted
+with a passphrase, to set up a CI runner or another machine with 'auth import'.
+The passphrase is read from MISSION_CONTROL_BUNDLE_PASSPHRASE, --passphrase-file or stdin.`,
+	Example: `  mission-control auth export --out creds.mc
+  mission-control auth export --out creds.mc --expires-in 24h --passphrase-file pass.txt`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if exportOut == "" {
+			return fmt.Errorf("--out is required")
+		}
+
+		store, err := storage.NewProfileTokenStorage(cfg.Profile)
+		if err != nil {
+			return fmt.Errorf("failed to create token storage: %w", err)
+		}
+		token, err := store.LoadToken()
+		if err != nil {
+			return fmt.Errorf("no token to export for profile %s: %w", cfg.Profile, err)
+		}
+
+		settings, err := json.Marshal(config.ProfileFromConfig(cfg))
+		if err != nil {
+			return fmt.Errorf("failed to marshal profile: %w", err)
+		}
+
+		passphrase, err := readPassphrase(cmd.InOrStdin(), true)
+		if err != nil {
+			return err
+		}
+
+		bundle := &storage.Bundle{
+			Profile:   cfg.Profile,
+			CreatedAt: time.Now(),
+			Settings:  settings,
+			Token:     token,
+		}
+		if exportExpiresIn > 0 {
+			bundle.ExpiresAt = bundle.CreatedAt.Add(exportExpiresIn)
+		}
+
+		data, err := storage.SealBundle(bundle, passphrase)
+		if err != nil {
+			return err
+		}
+
+		if exportOut == "-" {
+			_, err := os.Stdout.Write(append(data, '\n'))
+			return err
+		}
+		if err := os.WriteFile(exportOut, data, 0600); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+
+		fmt.Printf("Exported profile %s to %s\n", cfg.Profile, exportOut)
+		if !bundle.ExpiresAt.IsZero() {
+			fmt.Printf("The bundle can be imported until %s\n", bundle.ExpiresAt.Format("2006-01-02 15:04:05"))
+		}
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Import a token and settings from an encrypted bundle",
+	Long: `Import a bundle written by 'auth export' into the active credential backend.
+The bundle's profile is used unless --profile is given. Use - to read the bundle from stdin;
+the passphrase must then come from MISSION_CONTROL_BUNDLE_PASSPHRASE or --passphrase-file.`,
+	Example: `  mission-control auth import creds.mc
+  mission-control --profile ci auth import creds.mc --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			if os.Getenv(storage.BundlePassphraseEnv) == "" && passphraseFile == "" {
+				return fmt.Errorf("reading the bundle from stdin needs %s or --passphrase-file", storage.BundlePassphraseEnv)
+			}
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		passphrase, err := readPassphrase(cmd.InOrStdin(), false)
+		if err != nil {
+			return err
+		}
+		bundle, err := storage.OpenBundle(data, passphrase)
+		if err != nil {
+			return err
+		}
+
+		name := bundle.Profile
+		if profile != "" {
+			name = cfg.Profile
+		}
+
+		store, err := storage.NewProfileTokenStorage(name)
+		if err != nil {
+			return fmt.Errorf("failed to create token storage: %w", err)
+		}
+		if _, err := store.LoadToken(); err == nil && !importForce {
+			return fmt.Errorf("profile %s already has a token - use --force to replace it", name)
+		}
+		if err := store.SaveToken(bundle.Token); err != nil {
+			return fmt.Errorf("failed to save token: %w", err)
+		}
+
+		if len(bundle.Settings) > 0 {
+			var settings config.Profile
+			if err := json.Unmarshal(bundle.Settings, &settings); err != nil {
+				return fmt.Errorf("failed to parse bundle settings: %w", err)
+			}
+			profiles, err := config.LoadProfiles()
+			if err != nil {
+				return err
+			}
+			profiles.Profiles[name] = &settings
+			if profiles.Current == "" {
+				profiles.Current = name
+			}
+			if err := profiles.Save(); err != nil {
+				return fmt.Errorf("failed to save profile %s: %w", name, err)
+			}
+		}
+
+		fmt.Printf("Imported profile %s into %s\n", name, store.Location())
+		if bundle.Token.IsExpired() {
+			fmt.Println("Note: Token is expired and will be refreshed on next use")
+		}
+		return nil
+	},
+}
+
+// readPassphrase returns the bundle passphrase from the environment, --passphrase-file
+// or stdin. A terminal is prompted without echo, twice when confirm is set so a
+// typo can't produce a bundle nobody can open; piped stdin gives its first line.
+func readPassphrase(stdin io.Reader, confirm bool) (string, error) {
+	if passphrase := os.Getenv(storage.BundlePassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		passphrase, err := promptPassphrase(f, "Bundle passphrase: ")
+		if err != nil || !confirm {
+			return passphrase, err
+		}
+		repeated, err := promptPassphrase(f, "Repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if repeated != passphrase {
+			return "", fmt.Errorf("passphrases don't match")
+		}
+		return passphrase, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Bundle passphrase: ")
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read passphrase from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptPassphrase reads a passphrase from the terminal without echoing it
+func promptPassphrase(tty *os.File, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+func init() {
+	authCmd.AddCommand(exportCmd)
+	authCmd.AddCommand(importCmd)
+
+	exportCmd.Flags().StringVarP(&exportOut, "out", "o", "", "Write the bundle to this file (- for stdout)")
+	exportCmd.Flags().DurationVar(&exportExpiresIn, "expires-in", 0, "Refuse to import the bundle after this long (default: never)")
+	importCmd.Flags().BoolVar(&importForce, "force", false, "Replace a token already stored for the profile")
+	for _, c := range []*cobra.Command{exportCmd, importCmd} {
+		c.Flags().StringVar(&passphraseFile, "passphrase-file", "", "Read the bundle passphrase from this file")
+	}
+}
@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/launch01/mission-control/internal/storage"
+)
+
+func TestReadPassphrase(t *testing.T) {
+	t.Setenv(storage.BundlePassphraseEnv, "")
+	passphrase, err := readPassphrase(strings.NewReader("piped passphrase\nrest\n"), true)
+	if err != nil || passphrase != "piped passphrase" {
+		t.Errorf("readPassphrase() = %q, %v; want the first line of piped stdin", passphrase, err)
+	}
+
+	t.Setenv(storage.BundlePassphraseEnv, "from env")
+	if passphrase, err := readPassphrase(strings.NewReader(""), true); err != nil || passphrase != "from env" {
+		t.Errorf("readPassphrase() = %q, %v; want the env passphrase", passphrase, err)
+	}
+}
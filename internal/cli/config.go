@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/launch01/mission-control/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configProject    bool
+	configShowOrigin bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Configuration file commands",
+	Long: `Read and write the config files. Values are resolved with this precedence, highest first:
+flags, environment variables (including .env files), the profile's saved settings, the project
+file (.mission-control.yaml in the working directory or a parent), the user file
+(~/.config/mission-control/config.yaml, or MISSION_CONTROL_CONFIG), built-in defaults.
+
+Saved settings belong to one profile, such as the client registered with a server by
+"auth login --server", so they win over the files, which apply to every profile.
+
+Project and .env files come with the directory you run in, so they can't set the settings
+that run programs or receive credentials: URLs of the MCP server and OAuth endpoints, the
+redirect URI, mcp.command, credentials.helper, policy and log.file.`,
+	// Config commands read the files themselves, so a bad setting can still be fixed
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:     "get <key>",
+	Short:   "Print the effective value of a setting",
+	Example: `  mission-control config get mcp.url`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, ok := config.LookupSetting(args[0]); !ok {
+			return fmt.Errorf("unknown config key %q - see 'mission-control config view'", args[0])
+		}
+		sources, err := config.LoadSources(configFlags(profile))
+		if err != nil {
+			return err
+		}
+		fmt.Println(sources.Lookup(args[0], sources.Lookup("profile", "").Value).Value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a value in the user config file, or the project file with --project",
+	Example: `  mission-control config set mcp.url http://127.0.0.1:3333
+  mission-control config set --project profile sandbox`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if setting, _ := config.LookupSetting(args[0]); setting.Sensitive && configProject {
+			return fmt.Errorf("%s can't be set in a project file - set it in the user file instead", args[0])
+		}
+		file, err := targetConfigFile()
+		if err != nil {
+			return err
+		}
+		if err := file.Set(args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Set %s in %s\n", args[0], file.Path)
+		return nil
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a value from the user config file, or the project file with --project",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := targetConfigFile()
+		if err != nil {
+			return err
+		}
+		removed, err := file.Unset(args[0])
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("%s is not set in %s", args[0], file.Path)
+		}
+		fmt.Printf("Removed %s from %s\n", args[0], file.Path)
+		return nil
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the values set in the config files",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sources, err := config.LoadSources(nil)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range []*config.File{sources.Project, sources.User} {
+			if file == nil || len(file.Values) == 0 {
+				continue
+			}
+			fmt.Printf("# %s\n", file.Path)
+			for _, key := range file.Keys() {
+				fmt.Printf("%s=%s\n", key, displayValue(key, file.Values[key]))
+			}
+		}
+		return nil
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Show the config files in use",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		userPath, err := config.UserConfigPath()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("User config: %s%s\n", userPath, missingSuffix(userPath))
+
+		if path := config.FindProjectConfig(); path != "" {
+			fmt.Printf("Project config: %s\n", path)
+		} else {
+			fmt.Println("Project config: none")
+		}
+		return nil
+	},
+}
+
+var configViewCmd = &cobra.Command{
+	Use:     "view",
+	Short:   "Show the effective value of every setting",
+	Example: `  mission-control config view --show-origin`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sources, err := config.LoadSources(configFlags(profile))
+		if err != nil {
+			return err
+		}
+
+		for _, v := range sources.Resolve(sources.Lookup("profile", "").Value) {
+			value := displayValue(v.Key, v.Value)
+			if configShowOrigin {
+				fmt.Printf("%-32s %-40s %s\n", v.Key, value, v.OriginString())
+			} else {
+				fmt.Printf("%-32s %s\n", v.Key, value)
+			}
+		}
+		return nil
+	},
+}
+
+// targetConfigFile reads the file written by set and unset: the nearest project file,
+// or a new one in the working directory, with --project, and the user file otherwise
+func targetConfigFile() (*config.File, error) {
+	if !configProject {
+		path, err := config.UserConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		return config.ReadFile(path)
+	}
+
+	path := config.FindProjectConfig()
+	if path == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		path = filepath.Join(dir, config.ProjectConfigFile)
+	}
+	return config.ReadFile(path)
+}
+
+// displayValue masks secret settings
+func displayValue(key, value string) string {
+	if setting, ok := config.LookupSetting(key); ok && setting.Secret && value != "" {
+		return "***"
+	}
+	return value
+}
+
+// missingSuffix notes a config file that doesn't exist yet
+func missingSuffix(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return " (not created yet)"
+	}
+	return ""
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configViewCmd)
+
+	for _, c := range []*cobra.Command{configSetCmd, configUnsetCmd} {
+		c.Flags().BoolVar(&configProject, "project", false, "Write the project config file ("+config.ProjectConfigFile+") instead of the user file")
+	}
+	configViewCmd.Flags().BoolVar(&configShowOrigin, "show-origin", false, "Show where each value comes from")
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/launch01/mission-control/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorJSON    bool
+	doctorLoadErr error
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the setup: config, callback port, credentials, token, MCP servers and clock",
+	Long: `Run setup checks and report each as pass, warn or fail with a hint on how to fix it.
+The command exits with status 1 when any check fails.`,
+	Example: `  mission-control doctor
+  mission-control doctor --json | jq '.checks[] | select(.status != "pass")'`,
+	// A config that doesn't load is reported as a failed check
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cfg, doctorLoadErr = loadConfig(profile)
+		if doctorLoadErr == nil {
+			// An invalid log setting is reported by the config check
+			configureLogging(cfg)
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var report *doctor.Report
+		if doctorLoadErr != nil {
+			report = doctor.LoadFailure(profile, doctorLoadErr)
+		} else {
+			report = doctor.Run(context.Background(), cfg)
+		}
+
+		if doctorJSON {
+			output, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Println(string(output))
+		} else {
+			printReport(report)
+		}
+
+		if report.Failed() {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d of %d checks failed", report.Summary.Fail, len(report.Checks))
+		}
+		return nil
+	},
+}
+
+// printReport prints one line per check, with the hint under warnings and failures
+func printReport(report *doctor.Report) {
+	fmt.Printf("Profile: %s\n\n", report.Profile)
+	for _, check := range report.Checks {
+		fmt.Printf("[%s] %-24s %s\n", strings.ToUpper(string(check.Status)), check.Name, check.Message)
+		if check.Hint != "" && check.Status != doctor.StatusPass {
+			fmt.Printf("       %-24s hint: %s\n", "", check.Hint)
+		}
+	}
+	fmt.Printf("\n%d passed, %d warnings, %d failed\n", report.Summary.Pass, report.Summary.Warn, report.Summary.Fail)
+}
+
+func init() {
+	RootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+}
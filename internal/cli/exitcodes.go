@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/launch01/mission-control/internal/agent"
+	"github.com/launch01/mission-control/internal/mcp"
+	"github.com/launch01/mission-control/internal/policy"
+)
+
+// Process exit codes, so scripts can tell failure classes apart
+const (
+	ExitOK            = 0
+	ExitError         = 1
+	ExitAuth          = 3
+	ExitForbidden     = 4
+	ExitRateLimited   = 5
+	ExitInvalidParams = 6
+	ExitToolError     = 7
+	ExitUnavailable   = 8
+	ExitServerError   = 9
+	ExitPolicyDenied  = 10
+)
+
+// exitCode maps an error returned by a command to a process exit code
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, policy.ErrDenied):
+		return ExitPolicyDenied
+	case errors.Is(err, agent.ErrNotAuthenticated),
+		errors.Is(err, agent.ErrRefreshFailed),
+		errors.Is(err, mcp.ErrUnauthorized):
+		return ExitAuth
+	case errors.Is(err, agent.ErrMissingScopes),
+		errors.Is(err, mcp.ErrForbidden):
+		return ExitForbidden
+	case errors.Is(err, mcp.ErrRateLimited):
+		return ExitRateLimited
+	case errors.Is(err, mcp.ErrInvalidParams):
+		return ExitInvalidParams
+	case errors.Is(err, mcp.ErrToolFailed):
+		return ExitToolError
+	case errors.Is(err, mcp.ErrTransport):
+		return ExitUnavailable
+	case errors.Is(err, mcp.ErrServer):
+		return ExitServerError
+	}
+	return ExitError
+}
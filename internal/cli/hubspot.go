@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/launch01/mission-control/internal/agent"
 	"github.com/spf13/cobra"
 )
 
@@ -31,10 +30,11 @@ var searchContactsCmd = &cobra.Command{
 	Short: "Search for contacts",
 	Example: `  mission-control hubspot contacts search --email john@example.com`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ag, err := agent.NewAgent(cfg)
+		ag, err := newAgent()
 		if err != nil {
 			return fmt.Errorf("failed to create agent: %w", err)
 		}
+		defer ag.Close()
 
 		ctx := context.Background()
 
@@ -72,10 +72,11 @@ var createDealCmd = &cobra.Command{
 			return fmt.Errorf("--name is required")
 		}
 
-		ag, err := agent.NewAgent(cfg)
+		ag, err := newAgent()
 		if err != nil {
 			return fmt.Errorf("failed to create agent: %w", err)
 		}
+		defer ag.Close()
 
 		ctx := context.Background()
 
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/launch01/mission-control/internal/agent"
+	"github.com/launch01/mission-control/internal/gateway"
+	"github.com/launch01/mission-control/internal/logging"
+	"github.com/launch01/mission-control/internal/oauth"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveStdio bool
+	serveHTTP  string
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run mission-control as an MCP server",
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the upstream MCP tools, resources and prompts with mission-control's credentials",
+	Long: `Serve re-exposes the tools, resources and prompts of the configured MCP servers to
+downstream MCP clients such as desktop AI apps. mission-control injects and refreshes
+the HubSpot OAuth token itself, so downstream clients never handle HubSpot credentials.`,
+	Example: `  mission-control mcp serve --stdio
+  mission-control mcp serve --http :3334`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveStdio == (serveHTTP != "") {
+			return fmt.Errorf("exactly one of --stdio or --http is required")
+		}
+
+		ag, err := agent.NewAgent(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create agent: %w", err)
+		}
+		defer ag.Close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Refresh ahead of expiry so a gateway idle for hours never hands out a dead token
+		var health func() oauth.Health
+		if tokens := ag.KeepAlive(ctx); tokens != nil {
+			health = tokens.Health
+		}
+
+		server := gateway.NewServer(ag)
+
+		if serveStdio {
+			logging.Info("Serving MCP over stdio")
+			return server.ServeStdio(ctx, os.Stdin, os.Stdout)
+		}
+
+		return serveGatewayHTTP(ctx, server, serveHTTP, health)
+	},
+}
+
+// serveGatewayHTTP serves the gateway on addr until ctx is canceled, with the token's
+// health at /healthz; a nil health means no server uses the OAuth token. A bare ":port"
+// binds to loopback only, since the gateway acts with the user's credentials.
+func serveGatewayHTTP(ctx context.Context, server *gateway.Server, addr string, health func() oauth.Health) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --http address %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		logging.Error("Warning: serving on non-loopback address %s exposes your HubSpot credentials to the network", host)
+		if ip != nil && ip.IsUnspecified() {
+			// Clients reach every interface, under names we can't list
+			server.AllowHost("*")
+		} else {
+			server.AllowHost(host)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", server)
+	mux.HandleFunc("/healthz", healthHandler(health))
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	srv := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	logging.Info("Serving MCP over HTTP at http://%s", listener.Addr())
+	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("MCP server error: %w", err)
+	}
+	return nil
+}
+
+// healthHandler reports the token's health, failing with 503 while it is unusable
+func healthHandler(health func() oauth.Health) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := "ok"
+		var token *oauth.Health
+		if health != nil {
+			h := health()
+			token = &h
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if token != nil && !token.Healthy {
+			status = "unhealthy"
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(struct {
+			Status string        `json:"status"`
+			Token  *oauth.Health `json:"token,omitempty"`
+		}{status, token})
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(mcpCmd)
+	mcpCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().BoolVar(&serveStdio, "stdio", false, "Serve MCP over stdin/stdout")
+	serveCmd.Flags().StringVar(&serveHTTP, "http", "", "Serve MCP over HTTP on this address (e.g. :3334)")
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/launch01/mission-control/internal/oauth"
+)
+
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		health     func() oauth.Health
+		wantStatus int
+		wantToken  bool
+	}{
+		{"no OAuth servers", nil, http.StatusOK, false},
+		{"fresh token", func() oauth.Health { return oauth.Health{Healthy: true, ExpiresAt: time.Now().Add(time.Hour)} }, http.StatusOK, true},
+		{"no token", func() oauth.Health { return oauth.Health{LastError: "no stored token"} }, http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			healthHandler(tt.health)(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if _, ok := body["token"]; ok != tt.wantToken {
+				t.Errorf("body = %v, want token reported: %v", body, tt.wantToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/launch01/mission-control/internal/policy"
+	"github.com/spf13/cobra"
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Tool policy commands",
+}
+
+var policyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Dry-run a tool call against the policy without calling the tool",
+	Example: `  mission-control policy test --name hubspot/search_contacts --input '{"objectType": "tickets", "limit": 500}'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if toolName == "" {
+			return fmt.Errorf("--name is required")
+		}
+
+		inputArgs := map[string]interface{}{}
+		if toolInput != "" {
+			if err := json.Unmarshal([]byte(toolInput), &inputArgs); err != nil {
+				return fmt.Errorf("invalid JSON input: %w", err)
+			}
+		}
+
+		ag, err := newAgent()
+		if err != nil {
+			return fmt.Errorf("failed to create agent: %w", err)
+		}
+		defer ag.Close()
+
+		if ag.Policy() == nil {
+			fmt.Println("No policy configured - all tool calls are allowed")
+			return nil
+		}
+
+		decision := ag.EvaluatePolicy(toolName, inputArgs)
+
+		fmt.Printf("Policy: %s\n", ag.Policy().Path())
+		fmt.Printf("Tool: %s\n", toolName)
+		if decision.Allowed {
+			fmt.Println("Decision: allow")
+		} else {
+			fmt.Println("Decision: deny")
+		}
+		if decision.Confirm {
+			fmt.Println("Confirmation required: yes")
+		}
+		if len(decision.Reasons) > 0 {
+			fmt.Println("Reasons:")
+			for _, reason := range decision.Reasons {
+				fmt.Printf("  - %s\n", reason)
+			}
+		}
+
+		if !decision.Allowed {
+			return &policy.DeniedError{Tool: toolName, Reasons: decision.Reasons}
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(policyCmd)
+	policyCmd.AddCommand(policyTestCmd)
+
+	policyTestCmd.Flags().StringVarP(&toolName, "name", "n", "", "Tool name (required)")
+	policyTestCmd.Flags().StringVarP(&toolInput, "input", "i", "", "Tool input as JSON")
+}
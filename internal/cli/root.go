@@ -1,10 +1,16 @@
 package cli
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/google/uuid"
+	"github.com/launch01/mission-control/internal/agent"
 	"github.com/launch01/mission-control/internal/config"
+	"github.com/launch01/mission-control/internal/logging"
 	"github.com/spf13/cobra"
 )
 
@@ -12,6 +18,11 @@ var (
 	cfg       *config.Config
 	mcpURL    string
 	authMode  string
+	assumeYes bool
+	profile   string
+	logLevel  string
+	logFormat string
+	logFile   string
 )
 
 // RootCmd represents the base command
@@ -21,32 +32,91 @@ var RootCmd = &cobra.Command{
 	Long:  `Mission Control is a CLI tool for interacting with HubSpot via MCP (Model Context Protocol) using OAuth 2.0 authentication.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		var err error
-		cfg, err = config.Load()
+		cfg, err = loadConfig(profile)
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+		return configureLogging(cfg)
+	},
+}
 
-		// Override config with flags if provided
-		if mcpURL != "" {
-			cfg.MCP.URL = mcpURL
-		}
-		if authMode != "" {
-			cfg.MCP.AuthMode = authMode
-		}
+// configFlags maps the config keys overridden by global flags to the flag values
+func configFlags(name string) map[string]string {
+	return map[string]string{
+		"profile":       name,
+		"mcp.url":       mcpURL,
+		"mcp.auth_mode": authMode,
+		"log.level":     logLevel,
+		"log.format":    logFormat,
+		"log.file":      logFile,
+	}
+}
 
-		return nil
-	},
+// loadConfig loads the configuration of the named profile, with the global flags applied
+func loadConfig(name string) (*config.Config, error) {
+	return config.LoadWithFlags(configFlags(name))
+}
+
+// runID tells apart the log records of concurrent or successive invocations
+var runID = uuid.NewString()
+
+// configureLogging applies the log settings of cfg and tags every record with the profile
+func configureLogging(cfg *config.Config) error {
+	err := logging.Configure(logging.Options{
+		Level:  cfg.Log.Level,
+		Format: cfg.Log.Format,
+		File:   cfg.Log.File,
+	})
+	if err != nil {
+		return err
+	}
+	logging.With("profile", cfg.Profile, "run_id", runID)
+	return nil
 }
 
 func init() {
-	RootCmd.PersistentFlags().StringVar(&mcpURL, "mcp-url", "", "MCP server URL (default from HUBSPOT_MCP_URL or http://127.0.0.1:3333)")
+	RootCmd.PersistentFlags().StringVar(&mcpURL, "mcp-url", "", "MCP server URL (default from HUBSPOT_MCP_URL, mcp.url in the config files or http://127.0.0.1:3333)")
 	RootCmd.PersistentFlags().StringVar(&authMode, "auth-mode", "", "Authentication mode: header or context (default: header)")
+	RootCmd.PersistentFlags().StringVar(&profile, "profile", "", "HubSpot portal profile to use (default from MISSION_CONTROL_PROFILE, the config files or 'auth switch')")
+	RootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Log level: debug, info, warn or error (default: info)")
+	RootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Log format: text or json (default: text)")
+	RootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Write logs to this file, rotated at 10 MiB, instead of stderr")
+	RootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Confirm tool calls that the policy flags for confirmation")
+}
+
+// newAgent creates an agent that asks for confirmation on the terminal when the policy requires it
+func newAgent() (*agent.Agent, error) {
+	ag, err := agent.NewAgent(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ag.SetConfirmer(confirmToolCall)
+	return ag, nil
+}
+
+// confirmToolCall prompts on stderr unless --yes was given; non-interactive sessions are refused
+func confirmToolCall(tool string, args map[string]interface{}) bool {
+	if assumeYes {
+		return true
+	}
+
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	input, _ := json.Marshal(args)
+	fmt.Fprintf(os.Stderr, "Policy requires confirmation to call %s with %s\nProceed? [y/N] ", tool, input)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
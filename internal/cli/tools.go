@@ -5,13 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/launch01/mission-control/internal/agent"
+	"github.com/launch01/mission-control/internal/mcp"
 	"github.com/spf13/cobra"
 )
 
 var (
-	toolName  string
-	toolInput string
+	toolName   string
+	toolInput  string
+	toolServer string
 )
 
 var toolsCmd = &cobra.Command{
@@ -23,13 +24,20 @@ var listToolsCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available MCP tools",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ag, err := agent.NewAgent(cfg)
+		ag, err := newAgent()
 		if err != nil {
 			return fmt.Errorf("failed to create agent: %w", err)
 		}
 
+		defer ag.Close()
+
 		ctx := context.Background()
-		tools, err := ag.ListTools(ctx)
+		var tools []mcp.Tool
+		if toolServer != "" {
+			tools, err = ag.ListServerTools(ctx, toolServer)
+		} else {
+			tools, err = ag.ListTools(ctx)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to list tools: %w", err)
 		}
@@ -52,7 +60,8 @@ var listToolsCmd = &cobra.Command{
 var callToolCmd = &cobra.Command{
 	Use:   "call",
 	Short: "Call an MCP tool",
-	Example: `  mission-control tools call --name search_contacts --input '{"query": "example.com", "limit": 10}'`,
+	Example: `  mission-control tools call --name search_contacts --input '{"query": "example.com", "limit": 10}'
+  mission-control tools call --name billing/get_invoice --input '{"id": "inv_123"}'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if toolName == "" {
 			return fmt.Errorf("--name is required")
@@ -66,10 +75,11 @@ var callToolCmd = &cobra.Command{
 			return fmt.Errorf("invalid JSON input: %w", err)
 		}
 
-		ag, err := agent.NewAgent(cfg)
+		ag, err := newAgent()
 		if err != nil {
 			return fmt.Errorf("failed to create agent: %w", err)
 		}
+		defer ag.Close()
 
 		ctx := context.Background()
 		result, err := ag.CallTool(ctx, toolName, inputArgs)
@@ -87,11 +97,69 @@ var callToolCmd = &cobra.Command{
 	},
 }
 
+var batchToolsCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Call several MCP tools in one batch",
+	Example: `  mission-control tools batch --input '[{"name": "search_contacts", "arguments": {"query": "a.com"}}, {"name": "search_contacts", "arguments": {"query": "b.com"}}]'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if toolInput == "" {
+			return fmt.Errorf("--input is required")
+		}
+
+		var calls []mcp.ToolCall
+		if err := json.Unmarshal([]byte(toolInput), &calls); err != nil {
+			return fmt.Errorf("invalid JSON input: %w", err)
+		}
+
+		ag, err := newAgent()
+		if err != nil {
+			return fmt.Errorf("failed to create agent: %w", err)
+		}
+		defer ag.Close()
+
+		ctx := context.Background()
+		results, err := ag.CallBatch(ctx, calls)
+		if err != nil {
+			return fmt.Errorf("batch call failed: %w", err)
+		}
+
+		// Print one entry per call, keeping the input order
+		output := make([]map[string]interface{}, len(results))
+		failed := 0
+		for i, result := range results {
+			entry := map[string]interface{}{"name": calls[i].Name}
+			if result.Err != nil {
+				entry["error"] = result.Err.Error()
+				failed++
+			} else {
+				var formatted interface{}
+				json.Unmarshal(result.Result, &formatted)
+				entry["result"] = formatted
+			}
+			output[i] = entry
+		}
+
+		formatted, _ := json.MarshalIndent(output, "", "  ")
+		fmt.Println(string(formatted))
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d tool calls failed", failed, len(results))
+		}
+
+		return nil
+	},
+}
+
 func init() {
 	RootCmd.AddCommand(toolsCmd)
 	toolsCmd.AddCommand(listToolsCmd)
 	toolsCmd.AddCommand(callToolCmd)
+	toolsCmd.AddCommand(batchToolsCmd)
+
+	listToolsCmd.Flags().StringVarP(&toolServer, "server", "s", "", "Only list tools from this MCP server")
 
 	callToolCmd.Flags().StringVarP(&toolName, "name", "n", "", "Tool name (required)")
 	callToolCmd.Flags().StringVarP(&toolInput, "input", "i", "", "Tool input as JSON (required)")
+
+	batchToolsCmd.Flags().StringVarP(&toolInput, "input", "i", "", "JSON array of {name, arguments} calls (required)")
 }
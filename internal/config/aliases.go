@@ -0,0 +1,260 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Alias is a named tool call with preset arguments, run with 'mission-control x <alias>'.
+// String arguments may hold text/template placeholders such as {{.Args.owner}}, filled
+// from the invocation's --owner flag.
+type Alias struct {
+	Name        string                 `yaml:"-"`
+	Tool        string                 `yaml:"tool"`
+	Description string                 `yaml:"description,omitempty"`
+	Args        map[string]interface{} `yaml:"args,omitempty"`
+
+	// Source is the config file defining the alias
+	Source string `yaml:"-"`
+}
+
+// ToolDefaults maps tool names, bare or "server/tool", to arguments filled in when a
+// call leaves them out
+type ToolDefaults map[string]map[string]interface{}
+
+// sections are the parts of a config file that aren't settings
+type sections struct {
+	Aliases  map[string]*Alias `yaml:"aliases,omitempty"`
+	Defaults ToolDefaults      `yaml:"defaults,omitempty"`
+}
+
+// isSectionKey reports whether a dotted key read by viper belongs to a section
+func isSectionKey(key string) bool {
+	return strings.HasPrefix(key, "aliases.") || strings.HasPrefix(key, "defaults.")
+}
+
+// readSections reads the aliases and defaults of a config file. They are decoded
+// directly rather than through viper, which would lowercase argument names.
+func readSections(f *File) error {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", f.Path, err)
+	}
+
+	var s sections
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", f.Path, err)
+	}
+	for name, alias := range s.Aliases {
+		if alias == nil || alias.Tool == "" {
+			return fmt.Errorf("config file %s: alias %q has no tool", f.Path, name)
+		}
+		if strings.ContainsAny(name, " /") {
+			return fmt.Errorf("config file %s: invalid alias name %q", f.Path, name)
+		}
+		alias.Name, alias.Source = name, f.Path
+		if err := alias.parse(); err != nil {
+			return fmt.Errorf("config file %s: %w", f.Path, err)
+		}
+	}
+
+	f.Aliases, f.Defaults = s.Aliases, s.Defaults
+	return nil
+}
+
+// Aliases returns the aliases of the user config file. Project files can't define
+// aliases: like Sensitive settings, they come with a checked out repository.
+func (s *Sources) Aliases() map[string]*Alias {
+	aliases := map[string]*Alias{}
+	if s.User != nil {
+		for name, alias := range s.User.Aliases {
+			aliases[name] = alias
+		}
+	}
+	return aliases
+}
+
+// ToolDefaults returns the tool defaults of the user config file; project files
+// can't add arguments to tool calls
+func (s *Sources) ToolDefaults() ToolDefaults {
+	defaults := ToolDefaults{}
+	if s.User != nil {
+		for tool, args := range s.User.Defaults {
+			defaults[tool] = args
+		}
+	}
+	return defaults
+}
+
+// Apply returns args with the defaults for tool filled in; args is not modified
+func (d ToolDefaults) Apply(tool string, args map[string]interface{}) map[string]interface{} {
+	defaults := d[tool]
+	if len(defaults) == 0 {
+		return args
+	}
+
+	merged := make(map[string]interface{}, len(args)+len(defaults))
+	for name, value := range defaults {
+		merged[name] = value
+	}
+	for name, value := range args {
+		merged[name] = value
+	}
+	return merged
+}
+
+// placeholderArg matches the flag names referenced by placeholders, e.g. {{.Args.owner}}
+var placeholderArg = regexp.MustCompile(`\.Args\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// missingArg matches the error of a placeholder whose flag wasn't given
+var missingArg = regexp.MustCompile(`map has no entry for key "([^"]+)"`)
+
+// parse checks the alias's placeholders
+func (a *Alias) parse() error {
+	return walkStrings(a.Args, func(s string) (interface{}, error) {
+		if _, err := a.template(s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	})
+}
+
+func (a *Alias) template(s string) (*template.Template, error) {
+	tmpl, err := template.New(a.Name).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("alias %s: %w", a.Name, err)
+	}
+	return tmpl, nil
+}
+
+// Params returns the flag names the alias's placeholders reference, sorted
+func (a *Alias) Params() []string {
+	var params []string
+	seen := map[string]bool{}
+	walkStrings(a.Args, func(s string) (interface{}, error) {
+		for _, m := range placeholderArg.FindAllStringSubmatch(s, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				params = append(params, m[1])
+			}
+		}
+		return s, nil
+	})
+	sort.Strings(params)
+	return params
+}
+
+// Render builds the tool arguments from the alias's preset arguments and the
+// invocation's flags. Flags referenced by placeholders fill them in as strings;
+// any other flag sets the argument of the same name, decoded as JSON when it is
+// valid JSON (numbers, booleans, arrays, objects) and as a string otherwise.
+func (a *Alias) Render(flags map[string]string) (map[string]interface{}, error) {
+	data := struct{ Args map[string]string }{Args: flags}
+	args, err := deepCopy(a.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = walkStrings(args, func(s string) (interface{}, error) {
+		if !strings.Contains(s, "{{") {
+			return s, nil
+		}
+		tmpl, err := a.template(s)
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			if m := missingArg.FindStringSubmatch(err.Error()); m != nil {
+				return nil, fmt.Errorf("alias %s needs --%s", a.Name, m[1])
+			}
+			return nil, fmt.Errorf("alias %s: %w", a.Name, err)
+		}
+		return out.String(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := map[string]bool{}
+	for _, param := range a.Params() {
+		referenced[param] = true
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	for name, value := range flags {
+		if !referenced[name] {
+			args[name] = decodeFlag(value)
+		}
+	}
+	return args, nil
+}
+
+// decodeFlag decodes a flag value as JSON, falling back to the string itself
+func decodeFlag(value string) interface{} {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+		return decoded
+	}
+	return value
+}
+
+// deepCopy copies decoded YAML arguments so rendering leaves the alias unchanged
+func deepCopy(args map[string]interface{}) (map[string]interface{}, error) {
+	if args == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy alias arguments: %w", err)
+	}
+	var copied map[string]interface{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("failed to copy alias arguments: %w", err)
+	}
+	return copied, nil
+}
+
+// walkStrings replaces every string in a decoded YAML or JSON value, in place
+func walkStrings(value interface{}, fn func(string) (interface{}, error)) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if s, ok := item.(string); ok {
+				replaced, err := fn(s)
+				if err != nil {
+					return err
+				}
+				v[key] = replaced
+				continue
+			}
+			if err := walkStrings(item, fn); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if s, ok := item.(string); ok {
+				replaced, err := fn(s)
+				if err != nil {
+					return err
+				}
+				v[i] = replaced
+				continue
+			}
+			if err := walkStrings(item, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
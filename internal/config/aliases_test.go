@@ -0,0 +1,122 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const aliasConfig = `
+mcp:
+  url: http://user.example
+aliases:
+  open-deals:
+    tool: hubspot-search-objects
+    description: Open deals owned by someone
+    args:
+      objectType: deals
+      limit: 50
+      filterGroups:
+        - filters:
+            - propertyName: hubspot_owner_id
+              operator: EQ
+              value: "{{.Args.owner}}"
+defaults:
+  hubspot-search-objects:
+    limit: 20
+    properties: [dealname, amount]
+`
+
+func TestAliasRender(t *testing.T) {
+	userPath, projectDir := setupConfigDirs(t)
+	writeConfig(t, userPath, aliasConfig)
+	// Project files can't add arguments to tool calls or redefine aliases
+	writeConfig(t, filepath.Join(projectDir, ProjectConfigFile), `
+aliases:
+  open-deals:
+    tool: hubspot-delete-objects
+  wipe:
+    tool: hubspot-delete-objects
+defaults:
+  hubspot-search-objects:
+    limit: 10
+    archived: true
+`)
+
+	cfg, err := LoadWithFlags(nil)
+	if err != nil {
+		t.Fatalf("LoadWithFlags() error = %v", err)
+	}
+	alias := cfg.Aliases["open-deals"]
+	if alias == nil || alias.Tool != "hubspot-search-objects" || alias.Source != userPath || cfg.Aliases["wipe"] != nil {
+		t.Fatalf("Aliases = %+v", cfg.Aliases)
+	}
+	if params := alias.Params(); !reflect.DeepEqual(params, []string{"owner"}) {
+		t.Errorf("Params() = %v", params)
+	}
+
+	if _, err := alias.Render(nil); err == nil || err.Error() != "alias open-deals needs --owner" {
+		t.Errorf("Render() without --owner error = %v", err)
+	}
+
+	args, err := alias.Render(map[string]string{"owner": "123", "limit": "5"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	filter := args["filterGroups"].([]interface{})[0].(map[string]interface{})["filters"].([]interface{})[0].(map[string]interface{})
+	if filter["value"] != "123" {
+		t.Errorf("filter value = %#v, want the --owner string", filter["value"])
+	}
+	if args["limit"] != float64(5) || args["objectType"] != "deals" {
+		t.Errorf("Render() = %v", args)
+	}
+	if alias.Args["filterGroups"].([]interface{})[0].(map[string]interface{})["filters"].([]interface{})[0].(map[string]interface{})["value"] != "{{.Args.owner}}" {
+		t.Error("Render() modified the alias")
+	}
+
+	defaults := cfg.ToolDefaults["hubspot-search-objects"]
+	if defaults["limit"] != 20 || defaults["properties"] == nil || defaults["archived"] != nil {
+		t.Errorf("ToolDefaults = %v, want the user file's only", cfg.ToolDefaults)
+	}
+	merged := cfg.ToolDefaults.Apply("hubspot-search-objects", map[string]interface{}{"limit": 3})
+	if merged["limit"] != 3 || merged["properties"] == nil {
+		t.Errorf("Apply() = %v", merged)
+	}
+}
+
+func TestAliasSurvivesConfigSet(t *testing.T) {
+	userPath, _ := setupConfigDirs(t)
+	writeConfig(t, userPath, aliasConfig)
+
+	file, err := ReadFile(userPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if err := file.Set("mcp.auth_mode", "context"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	file, err = ReadFile(userPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if file.Aliases["open-deals"] == nil || file.Defaults["hubspot-search-objects"] == nil {
+		t.Errorf("aliases or defaults lost after Set(): %+v", file)
+	}
+	if file.Values["mcp.url"] != "http://user.example" || file.Values["mcp.auth_mode"] != "context" {
+		t.Errorf("Values = %v", file.Values)
+	}
+}
+
+func TestInvalidAlias(t *testing.T) {
+	userPath, _ := setupConfigDirs(t)
+	for _, content := range []string{
+		"aliases:\n  broken:\n    args: {limit: 5}\n",
+		"aliases:\n  broken:\n    tool: t\n    args: {value: \"{{.Args.owner\"}\n",
+	} {
+		writeConfig(t, userPath, content)
+		if _, err := ReadFile(userPath); err == nil {
+			t.Errorf("ReadFile() should reject %q", content)
+		}
+	}
+}
@@ -3,25 +3,75 @@ package config
 import (
 	"fmt"
 	"os"
-
-	"github.com/spf13/viper"
+	"path/filepath"
+	"strings"
 )
 
 const (
-	// OAuth endpoints
-	HubSpotAuthURL  = "https://app.hubspot.com/oauth/authorize"
-	HubSpotTokenURL = "https://api.hubapi.com/oauth/v1/token"
+	// DefaultHubSpotAuthURL is the authorization page of the "hubspot" OAuth provider preset
+	DefaultHubSpotAuthURL = "https://app.hubspot.com/oauth/authorize"
+	// DefaultAPIBaseURL serves the "hubspot" preset's token, revocation and token metadata endpoints
+	DefaultAPIBaseURL = "https://api.hubapi.com"
 
 	// Default values
 	DefaultRedirectURI = "http://127.0.0.1:8400/oauth/callback"
 	DefaultMCPURL      = "http://127.0.0.1:3333"
 	DefaultCallbackPort = "8400"
+
+	// DefaultProfile is used when no profile is selected
+	DefaultProfile = "default"
+
+	// DefaultScopes are requested when neither the environment nor the profile sets scopes
+	DefaultScopes = "crm.objects.contacts.read crm.objects.contacts.write crm.objects.companies.read crm.objects.companies.write crm.objects.deals.read crm.objects.deals.write"
+
+	// DefaultServerName names the primary HubSpot MCP server in tool namespaces
+	DefaultServerName = "hubspot"
+	// DefaultStdioTokenEnv is the variable the HubSpot MCP server reads its token from
+	DefaultStdioTokenEnv = "PRIVATE_APP_ACCESS_TOKEN"
+)
+
+// Transports for reaching an MCP server
+const (
+	TransportHTTP  = "http"
+	TransportStdio = "stdio"
+)
+
+// Token sources for authenticating to an MCP server
+const (
+	// TokenSourceOAuth uses the HubSpot token managed by mission-control
+	TokenSourceOAuth = "oauth"
+	// TokenSourceNone sends no token
+	TokenSourceNone = "none"
+	// TokenSourceEnvPrefix reads a static token from the named environment variable, e.g. "env:BILLING_TOKEN"
+	TokenSourceEnvPrefix = "env:"
 )
 
 // Config holds application configuration
 type Config struct {
+	// Profile names the HubSpot portal profile whose token and settings are used
+	Profile string
 	HubSpot HubSpotConfig
+	OAuth   OAuthConfig
 	MCP     MCPConfig
+
+	// PolicyFile is the tool policy enforced on every call; empty means no policy
+	PolicyFile string
+
+	// Log configures the diagnostic logs
+	Log LogConfig
+
+	// Aliases are the tool call aliases of the config files, by name
+	Aliases map[string]*Alias
+	// ToolDefaults are filled into tool calls that leave the arguments out
+	ToolDefaults ToolDefaults
+}
+
+// LogConfig holds logging configuration
+type LogConfig struct {
+	Level  string
+	Format string
+	// File receives the logs instead of stderr
+	File string
 }
 
 // HubSpotConfig holds HubSpot OAuth configuration
@@ -30,42 +80,263 @@ type HubSpotConfig struct {
 	ClientSecret string
 	RedirectURI  string
 	Scopes       string
+
+	// APIBaseURL is the base of the HubSpot OAuth token endpoints (HUBSPOT_API_URL),
+	// overridable so a local stand-in can be used; empty uses the provider preset's
+	APIBaseURL string
+	// AuthURL overrides the HubSpot authorization page (HUBSPOT_AUTH_URL)
+	AuthURL string
+}
+
+// OAuthConfig selects the OAuth provider the login flow talks to. Provider names
+// a preset ("hubspot", "hubspot-eu1") or "custom"; the other fields override the
+// preset and are required for a custom provider.
+type OAuthConfig struct {
+	Provider       string
+	AuthURL        string
+	TokenURL       string
+	RevokeURL      string
+	AuthParams     map[string]string // extra authorization request parameters
+	ScopeSeparator string
+	ClientAuth     string // "body" (default) or "basic"
+	// Resource is the RFC 8707 resource indicator sent with authorization and token requests
+	Resource string
 }
 
-// MCPConfig holds MCP server configuration
+// MCPConfig holds MCP server configuration.
+// URL, Command, Transport and AuthMode describe the primary HubSpot server;
+// Servers lists any additional named servers.
 type MCPConfig struct {
-	URL      string
-	AuthMode string // "header" or "context"
+	URL       string
+	AuthMode  string // "header" or "context"
+	Transport string // "http" or "stdio"
+	Command   string
+	Servers   []MCPServerConfig
+}
+
+// MCPServerConfig describes one named MCP server
+type MCPServerConfig struct {
+	Name        string
+	Transport   string // "http" or "stdio"
+	URL         string
+	Command     string
+	Args        []string
+	AuthMode    string // "header" or "context"
+	TokenSource string // "oauth", "none" or "env:VAR"
+	TokenEnv    string // variable carrying the token to stdio servers
 }
 
-// Load loads configuration from environment variables
+// Load loads configuration from environment variables for the selected profile
 func Load() (*Config, error) {
-	viper.SetEnvPrefix("HUBSPOT")
-	viper.AutomaticEnv()
+	return LoadProfile("")
+}
+
+// LoadProfile loads configuration for the named profile. An empty name selects
+// MISSION_CONTROL_PROFILE, then the config files, then the profile chosen with
+// 'auth switch', then "default".
+func LoadProfile(name string) (*Config, error) {
+	return LoadWithFlags(map[string]string{"profile": name})
+}
+
+// LoadWithFlags loads configuration with values given on the command line, keyed by
+// setting, e.g. "mcp.url". Precedence, highest first: flags, environment, the profile's
+// saved settings, project config file, user config file, defaults.
+func LoadWithFlags(flags map[string]string) (*Config, error) {
+	sources, err := LoadSources(flags)
+	if err != nil {
+		return nil, err
+	}
+
+	name := sources.Lookup("profile", "").Value
+	get := func(key string) string {
+		return sources.Lookup(key, name).Value
+	}
+	sources.Export(name)
 
 	cfg := &Config{
+		Profile: name,
 		HubSpot: HubSpotConfig{
-			ClientID:     getEnvOrDefault("HUBSPOT_CLIENT_ID", ""),
-			ClientSecret: getEnvOrDefault("HUBSPOT_CLIENT_SECRET", ""),
-			RedirectURI:  getEnvOrDefault("HUBSPOT_REDIRECT_URI", DefaultRedirectURI),
-			Scopes:       getEnvOrDefault("HUBSPOT_SCOPES", "crm.objects.contacts.read crm.objects.contacts.write crm.objects.companies.read crm.objects.companies.write crm.objects.deals.read crm.objects.deals.write"),
+			ClientID:     get("hubspot.client_id"),
+			ClientSecret: get("hubspot.client_secret"),
+			RedirectURI:  get("hubspot.redirect_uri"),
+			Scopes:       get("hubspot.scopes"),
+			APIBaseURL:   get("hubspot.api_url"),
+			AuthURL:      get("hubspot.auth_url"),
+		},
+		OAuth: OAuthConfig{
+			Provider:       get("oauth.provider"),
+			AuthURL:        get("oauth.auth_url"),
+			TokenURL:       get("oauth.token_url"),
+			RevokeURL:      get("oauth.revoke_url"),
+			ScopeSeparator: get("oauth.scope_separator"),
+			ClientAuth:     get("oauth.client_auth"),
+			Resource:       get("oauth.resource"),
 		},
 		MCP: MCPConfig{
-			URL:      getEnvOrDefault("HUBSPOT_MCP_URL", DefaultMCPURL),
-			AuthMode: getEnvOrDefault("HUBSPOT_MCP_AUTH_MODE", "header"),
+			URL:       get("mcp.url"),
+			AuthMode:  get("mcp.auth_mode"),
+			Transport: get("mcp.transport"),
+			Command:   get("mcp.command"),
+		},
+		Log: LogConfig{
+			Level:  get("log.level"),
+			Format: get("log.format"),
+			File:   get("log.file"),
 		},
 	}
 
-	if cfg.HubSpot.ClientID == "" {
-		return nil, fmt.Errorf("HUBSPOT_CLIENT_ID is required")
+	cfg.PolicyFile = get("policy")
+	if cfg.PolicyFile == "" {
+		if path, err := DefaultPolicyPath(); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				cfg.PolicyFile = path
+			}
+		}
+	}
+
+	cfg.OAuth.AuthParams, err = parseParams(get("oauth.auth_params"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid oauth.auth_params (MISSION_CONTROL_OAUTH_AUTH_PARAMS): %w", err)
+	}
+
+	servers, err := loadServersFromEnv()
+	if err != nil {
+		return nil, err
 	}
+	cfg.MCP.Servers = servers
+
+	cfg.Aliases = sources.Aliases()
+	cfg.ToolDefaults = sources.ToolDefaults()
 
 	return cfg, nil
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// RequireClientID returns an error when no OAuth client ID is configured.
+// Only the OAuth flow needs one; private app tokens work without it.
+func (h HubSpotConfig) RequireClientID() error {
+	if h.ClientID == "" {
+		return fmt.Errorf("HUBSPOT_CLIENT_ID is required for OAuth login (or store a private app token with 'mission-control auth set-token')")
+	}
+	return nil
+}
+
+// DefaultPolicyPath returns the policy file used when MISSION_CONTROL_POLICY is not set
+func DefaultPolicyPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "mission-control", "policy.yaml"), nil
+}
+
+// ServerList returns every configured MCP server, with the primary HubSpot server first
+func (c MCPConfig) ServerList() []MCPServerConfig {
+	primary := MCPServerConfig{
+		Name:        DefaultServerName,
+		Transport:   c.Transport,
+		URL:         c.URL,
+		AuthMode:    c.AuthMode,
+		TokenSource: TokenSourceOAuth,
+		TokenEnv:    DefaultStdioTokenEnv,
+	}
+	if c.Command != "" {
+		fields := strings.Fields(c.Command)
+		primary.Command = fields[0]
+		primary.Args = fields[1:]
+	}
+	primary.applyDefaults()
+
+	return append([]MCPServerConfig{primary}, c.Servers...)
+}
+
+// applyDefaults fills in the transport and auth mode when they are not set
+func (s *MCPServerConfig) applyDefaults() {
+	if s.Transport == "" {
+		if s.Command != "" {
+			s.Transport = TransportStdio
+		} else {
+			s.Transport = TransportHTTP
+		}
+	}
+	if s.AuthMode == "" {
+		s.AuthMode = "header"
+	}
+	if s.TokenSource == "" {
+		s.TokenSource = TokenSourceNone
+	}
+}
+
+// loadServersFromEnv reads additional servers named in MCP_SERVERS (comma-separated).
+// Each server NAME is configured with MCP_SERVER_<NAME>_URL, _COMMAND, _TRANSPORT,
+// _AUTH_MODE, _TOKEN_SOURCE and _TOKEN_ENV.
+func loadServersFromEnv() ([]MCPServerConfig, error) {
+	var servers []MCPServerConfig
+	for _, name := range strings.Split(os.Getenv("MCP_SERVERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == DefaultServerName || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid MCP server name %q", name)
+		}
+
+		prefix := "MCP_SERVER_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		server := MCPServerConfig{
+			Name:        name,
+			Transport:   os.Getenv(prefix + "TRANSPORT"),
+			URL:         os.Getenv(prefix + "URL"),
+			AuthMode:    os.Getenv(prefix + "AUTH_MODE"),
+			TokenSource: os.Getenv(prefix + "TOKEN_SOURCE"),
+			TokenEnv:    os.Getenv(prefix + "TOKEN_ENV"),
+		}
+		if command := strings.Fields(os.Getenv(prefix + "COMMAND")); len(command) > 0 {
+			server.Command = command[0]
+			server.Args = command[1:]
+		}
+		server.applyDefaults()
+
+		if err := server.Validate(); err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+// Validate checks that the server can be reached with its transport
+func (s MCPServerConfig) Validate() error {
+	switch s.Transport {
+	case TransportHTTP:
+		if s.URL == "" {
+			return fmt.Errorf("MCP server %q: url is required for the http transport", s.Name)
+		}
+	case TransportStdio:
+		if s.Command == "" {
+			return fmt.Errorf("MCP server %q: command is required for the stdio transport", s.Name)
+		}
+	default:
+		return fmt.Errorf("MCP server %q: unknown transport %q", s.Name, s.Transport)
+	}
+
+	if s.TokenSource != TokenSourceOAuth && s.TokenSource != TokenSourceNone && !strings.HasPrefix(s.TokenSource, TokenSourceEnvPrefix) {
+		return fmt.Errorf("MCP server %q: unknown token source %q", s.Name, s.TokenSource)
+	}
+	return nil
+}
+
+// parseParams parses "key=value,key=value" into a map
+func parseParams(s string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		params[strings.TrimSpace(key)] = strings.TrimSpace(value)
 	}
-	return defaultValue
+	return params, nil
 }
@@ -0,0 +1,458 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/launch01/mission-control/internal/logging"
+	"github.com/spf13/viper"
+	"github.com/subosito/gotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProjectConfigFile is looked up from the working directory upwards and overrides the
+	// user config file, except for Sensitive settings
+	ProjectConfigFile = ".mission-control.yaml"
+	// DotEnvFile in the working directory or the project directory is loaded into the
+	// environment, except for variables of Sensitive settings
+	DotEnvFile = ".env"
+	// ConfigPathEnv overrides the location of the user config file
+	ConfigPathEnv = "MISSION_CONTROL_CONFIG"
+)
+
+// Setting is a configuration key, the environment variable that overrides it and
+// its built-in default
+type Setting struct {
+	Key         string
+	Env         string
+	Default     string
+	Description string
+	// Secret values are masked when listed
+	Secret bool
+	// Export copies a value from a config file into the environment, for packages
+	// that read their settings from the environment
+	Export bool
+	// Sensitive settings run programs, receive credentials or relax safeguards, so
+	// project files and .env files, which come with a checked out repository, can't set them
+	Sensitive bool
+
+	// profile returns the field of a profile holding the setting, if profiles hold it
+	profile func(*Profile) *string
+}
+
+// Settings lists every configuration key in display order
+var Settings = []Setting{
+	{Key: "profile", Env: "MISSION_CONTROL_PROFILE", Default: DefaultProfile, Description: "HubSpot portal profile to use"},
+	{Key: "hubspot.client_id", Env: "HUBSPOT_CLIENT_ID", Description: "OAuth client ID", profile: func(p *Profile) *string { return &p.ClientID }},
+	{Key: "hubspot.client_secret", Env: "HUBSPOT_CLIENT_SECRET", Description: "OAuth client secret (optional with PKCE)", Secret: true, profile: func(p *Profile) *string { return &p.ClientSecret }},
+	{Key: "hubspot.redirect_uri", Env: "HUBSPOT_REDIRECT_URI", Default: DefaultRedirectURI, Description: "OAuth redirect URI", Sensitive: true},
+	{Key: "hubspot.scopes", Env: "HUBSPOT_SCOPES", Default: DefaultScopes, Description: "Scopes requested at login", profile: func(p *Profile) *string { return &p.Scopes }},
+	{Key: "hubspot.api_url", Env: "HUBSPOT_API_URL", Description: "Base URL of the HubSpot token endpoints", Sensitive: true},
+	{Key: "hubspot.auth_url", Env: "HUBSPOT_AUTH_URL", Description: "HubSpot authorization page", Sensitive: true},
+	{Key: "oauth.provider", Env: "MISSION_CONTROL_OAUTH_PROVIDER", Default: "hubspot", Description: "OAuth provider preset: hubspot, hubspot-eu1 or custom", profile: func(p *Profile) *string { return &p.OAuthProvider }},
+	{Key: "oauth.auth_url", Env: "MISSION_CONTROL_OAUTH_AUTH_URL", Description: "Authorization endpoint", Sensitive: true, profile: func(p *Profile) *string { return &p.OAuthAuthURL }},
+	{Key: "oauth.token_url", Env: "MISSION_CONTROL_OAUTH_TOKEN_URL", Description: "Token endpoint", Sensitive: true, profile: func(p *Profile) *string { return &p.OAuthTokenURL }},
+	{Key: "oauth.revoke_url", Env: "MISSION_CONTROL_OAUTH_REVOKE_URL", Description: "Revocation endpoint", Sensitive: true, profile: func(p *Profile) *string { return &p.OAuthRevokeURL }},
+	{Key: "oauth.auth_params", Env: "MISSION_CONTROL_OAUTH_AUTH_PARAMS", Description: "Extra authorization parameters, key=value,key=value"},
+	{Key: "oauth.scope_separator", Env: "MISSION_CONTROL_OAUTH_SCOPE_SEPARATOR", Description: "Separator joining scopes in the authorization request"},
+	{Key: "oauth.client_auth", Env: "MISSION_CONTROL_OAUTH_CLIENT_AUTH", Description: "Token endpoint client authentication: body or basic", profile: func(p *Profile) *string { return &p.OAuthClientAuth }},
+	{Key: "oauth.resource", Env: "MISSION_CONTROL_OAUTH_RESOURCE", Description: "RFC 8707 resource indicator", profile: func(p *Profile) *string { return &p.OAuthResource }},
+	{Key: "mcp.url", Env: "HUBSPOT_MCP_URL", Default: DefaultMCPURL, Description: "HubSpot MCP server URL", Sensitive: true, profile: func(p *Profile) *string { return &p.MCPURL }},
+	{Key: "mcp.auth_mode", Env: "HUBSPOT_MCP_AUTH_MODE", Default: "header", Description: "How the token is sent: header or context", profile: func(p *Profile) *string { return &p.MCPAuthMode }},
+	{Key: "mcp.transport", Env: "HUBSPOT_MCP_TRANSPORT", Description: "MCP transport: http or stdio"},
+	{Key: "mcp.command", Env: "HUBSPOT_MCP_COMMAND", Description: "Command running the MCP server over stdio", Sensitive: true},
+	{Key: "policy", Env: "MISSION_CONTROL_POLICY", Description: "Tool policy file (default: ~/.config/mission-control/policy.yaml if present)", Sensitive: true},
+	{Key: "credentials.backend", Env: "MISSION_CONTROL_CREDENTIAL_BACKEND", Default: "auto", Description: "Token storage: auto, keyring, file, env or helper", Export: true},
+	{Key: "credentials.helper", Env: "MISSION_CONTROL_CREDENTIAL_HELPER", Description: "Credential helper program", Export: true, Sensitive: true},
+	{Key: "log.level", Env: "MISSION_CONTROL_LOG_LEVEL", Description: "Log level: debug, info, warn or error (default: info, or debug with DEBUG=true)"},
+	{Key: "log.format", Env: "MISSION_CONTROL_LOG_FORMAT", Default: "text", Description: "Log format: text or json"},
+	{Key: "log.file", Env: "MISSION_CONTROL_LOG_FILE", Description: "Log file, rotated at 10 MiB (default: stderr)", Sensitive: true},
+}
+
+// LookupSetting returns the setting with the given key
+func LookupSetting(key string) (Setting, bool) {
+	for _, s := range Settings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Origins of resolved values
+const (
+	OriginFlag        = "flag"
+	OriginEnv         = "env"
+	OriginDotEnv      = ".env"
+	OriginProjectFile = "project file"
+	OriginUserFile    = "user file"
+	OriginProfile     = "profile"
+	OriginAuthSwitch  = "auth switch"
+	OriginDefault     = "default"
+)
+
+// Value is a resolved setting and where it came from
+type Value struct {
+	Key   string
+	Value string
+	// Origin is one of the Origin constants
+	Origin string
+	// Source details the origin: the flag, variable, file or profile name
+	Source string
+}
+
+// OriginString describes the origin, e.g. "env (HUBSPOT_CLIENT_ID)"
+func (v Value) OriginString() string {
+	if v.Source == "" {
+		return v.Origin
+	}
+	return v.Origin + " (" + v.Source + ")"
+}
+
+// File is a YAML config file with dotted keys, e.g. "mcp.url", and the aliases and
+// defaults sections
+type File struct {
+	Path     string
+	Values   map[string]string
+	Aliases  map[string]*Alias
+	Defaults ToolDefaults
+}
+
+// Sources are the layers values are resolved from. Precedence, highest first:
+// flags, environment (including .env files), the profile's saved settings, project
+// file, user file, built-in defaults. Saved settings outrank the files because they
+// belong to one profile, e.g. the client registered with a discovered server, while
+// the files apply to every profile.
+type Sources struct {
+	Flags    map[string]string
+	Project  *File
+	User     *File
+	Profiles *ProfileStore
+}
+
+// dotEnvVars records the variables set from .env files, by name, with the file they came from
+var dotEnvVars = map[string]string{}
+
+// exportedVars records the variables set from config files by Export, which don't
+// count as environment overrides
+var exportedVars = map[string]bool{}
+
+// LoadSources reads the config files and .env files. Flags maps setting keys to
+// values given on the command line; empty values are ignored.
+func LoadSources(flags map[string]string) (*Sources, error) {
+	s := &Sources{Flags: flags}
+
+	userPath, err := UserConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	if s.User, err = ReadFile(userPath); err != nil {
+		return nil, err
+	}
+
+	if path := FindProjectConfig(); path != "" {
+		if s.Project, err = ReadFile(path); err != nil {
+			return nil, err
+		}
+		for _, key := range s.Project.Keys() {
+			if setting, _ := LookupSetting(key); setting.Sensitive {
+				logging.Logger().Warn("Ignoring a setting project files can't set; set it in the user config file, the environment or a flag",
+					"key", key, "file", path)
+			}
+		}
+		if len(s.Project.Aliases) > 0 || len(s.Project.Defaults) > 0 {
+			logging.Logger().Warn("Ignoring aliases and tool defaults of a project file; define them in the user config file",
+				"file", path)
+		}
+		if err := loadDotEnv(filepath.Join(filepath.Dir(path), DotEnvFile)); err != nil {
+			return nil, err
+		}
+	}
+	if err := loadDotEnv(DotEnvFile); err != nil {
+		return nil, err
+	}
+
+	if s.Profiles, err = LoadProfiles(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup resolves a setting for the given profile
+func (s *Sources) Lookup(key, profile string) Value {
+	setting, _ := LookupSetting(key)
+	v := Value{Key: key}
+
+	if value := s.Flags[key]; value != "" {
+		v.Value, v.Origin = value, OriginFlag
+		return v
+	}
+	if setting.Env != "" {
+		if value := os.Getenv(setting.Env); value != "" && !exportedVars[setting.Env] {
+			v.Value, v.Origin, v.Source = value, OriginEnv, setting.Env
+			if path, ok := dotEnvVars[setting.Env]; ok {
+				v.Origin, v.Source = OriginDotEnv, path+": "+setting.Env
+			}
+			return v
+		}
+	}
+	if s.Profiles != nil && setting.profile != nil {
+		if p := s.Profiles.Profiles[profile]; p != nil {
+			if value := *setting.profile(p); value != "" {
+				v.Value, v.Origin, v.Source = value, OriginProfile, profile
+				return v
+			}
+		}
+	}
+	if value, file, origin := s.fileValue(setting); value != "" {
+		v.Value, v.Origin, v.Source = value, origin, file
+		return v
+	}
+	if s.Profiles != nil && key == "profile" && s.Profiles.Current != "" {
+		v.Value, v.Origin = s.Profiles.Current, OriginAuthSwitch
+		return v
+	}
+
+	v.Value, v.Origin = setting.Default, OriginDefault
+	return v
+}
+
+// fileValue returns a setting's value in the config files, with the file and its origin
+func (s *Sources) fileValue(setting Setting) (value, path, origin string) {
+	for _, layer := range []struct {
+		file   *File
+		origin string
+	}{{s.Project, OriginProjectFile}, {s.User, OriginUserFile}} {
+		if layer.file == nil || (setting.Sensitive && layer.origin == OriginProjectFile) {
+			continue
+		}
+		if value := layer.file.Values[setting.Key]; value != "" {
+			return value, layer.file.Path, layer.origin
+		}
+	}
+	return "", "", ""
+}
+
+// Inherited clears the fields of a profile whose value comes from the config files
+// or the defaults, so saving the profile doesn't pin values that should follow later
+// edits of the files
+func (s *Sources) Inherited(p *Profile) {
+	for _, setting := range Settings {
+		if setting.profile == nil {
+			continue
+		}
+		inherited, _, _ := s.fileValue(setting)
+		if inherited == "" {
+			inherited = setting.Default
+		}
+		if field := setting.profile(p); *field == inherited {
+			*field = ""
+		}
+	}
+}
+
+// Resolve resolves every setting for the given profile, in display order
+func (s *Sources) Resolve(profile string) []Value {
+	values := make([]Value, 0, len(Settings))
+	for _, setting := range Settings {
+		values = append(values, s.Lookup(setting.Key, profile))
+	}
+	return values
+}
+
+// Export sets the environment variables of Export settings whose value comes from a
+// config file
+func (s *Sources) Export(profile string) {
+	for _, setting := range Settings {
+		if !setting.Export {
+			continue
+		}
+		v := s.Lookup(setting.Key, profile)
+		if v.Origin == OriginProjectFile || v.Origin == OriginUserFile {
+			os.Setenv(setting.Env, v.Value)
+			exportedVars[setting.Env] = true
+		}
+	}
+}
+
+// UserConfigPath returns the user config file, ~/.config/mission-control/config.yaml
+// unless MISSION_CONTROL_CONFIG is set
+func UserConfigPath() (string, error) {
+	if path := os.Getenv(ConfigPathEnv); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "mission-control", "config.yaml"), nil
+}
+
+// FindProjectConfig returns the nearest project config file in the working directory
+// or its parents, or "" if there is none
+func FindProjectConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ReadFile reads a config file; a missing file has no values
+func ReadFile(path string) (*File, error) {
+	f := &File{Path: path, Values: map[string]string{}}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return f, nil
+		}
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	for _, key := range v.AllKeys() {
+		if isSectionKey(key) {
+			continue
+		}
+		if _, ok := LookupSetting(key); !ok {
+			return nil, fmt.Errorf("config file %s: unknown key %q", path, key)
+		}
+		// Lists, e.g. of scopes, are joined with spaces
+		if list, ok := v.Get(key).([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			f.Values[key] = strings.Join(items, " ")
+			continue
+		}
+		f.Values[key] = v.GetString(key)
+	}
+	if err := readSections(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Set sets a key in the file and writes it
+func (f *File) Set(key, value string) error {
+	if _, ok := LookupSetting(key); !ok {
+		return fmt.Errorf("unknown config key %q - see 'mission-control config view'", key)
+	}
+	f.Values[key] = value
+	return f.Write()
+}
+
+// Unset removes a key from the file and writes it, reporting whether it was set
+func (f *File) Unset(key string) (bool, error) {
+	if _, ok := f.Values[key]; !ok {
+		return false, nil
+	}
+	delete(f.Values, key)
+	return true, f.Write()
+}
+
+// Keys returns the keys set in the file, in display order
+func (f *File) Keys() []string {
+	var keys []string
+	for _, s := range Settings {
+		if _, ok := f.Values[s.Key]; ok {
+			keys = append(keys, s.Key)
+		}
+	}
+	return keys
+}
+
+// Write saves the file as nested YAML
+func (f *File) Write() error {
+	root := map[string]interface{}{}
+	keys := make([]string, 0, len(f.Values))
+	for key := range f.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts := strings.Split(key, ".")
+		node := root
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = f.Values[key]
+	}
+	if len(f.Aliases) > 0 {
+		root["aliases"] = f.Aliases
+	}
+	if len(f.Defaults) > 0 {
+		root["defaults"] = f.Defaults
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	data := buf.Bytes()
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(f.Path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// loadDotEnv sets the variables of a .env file that aren't already set in the
+// environment. Only the variables of settings that aren't Sensitive are loaded.
+func loadDotEnv(path string) error {
+	vars, err := gotenv.Read(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	allowed := map[string]bool{}
+	for _, setting := range Settings {
+		allowed[setting.Env] = !setting.Sensitive
+	}
+	for name, value := range vars {
+		if !allowed[name] {
+			logging.Logger().Debug("Ignoring a variable .env files can't set", "name", name, "file", path)
+			continue
+		}
+		if _, set := os.LookupEnv(name); set {
+			continue
+		}
+		os.Setenv(name, value)
+		dotEnvVars[name] = path
+	}
+	return nil
+}
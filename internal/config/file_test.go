@@ -0,0 +1,234 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupConfigDirs points the user config at a temporary home and runs the test in a
+// temporary project directory
+func setupConfigDirs(t *testing.T) (userPath, projectDir string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ConfigPathEnv, "")
+	for _, s := range Settings {
+		t.Setenv(s.Env, "")
+		os.Unsetenv(s.Env)
+	}
+
+	projectDir = t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	userPath, err = UserConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userPath, projectDir
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	userPath, projectDir := setupConfigDirs(t)
+
+	writeConfig(t, userPath, `
+hubspot:
+  client_id: user-client
+  scopes: [crm.objects.contacts.read, crm.objects.deals.read]
+mcp:
+  url: http://user.example
+  auth_mode: context
+`)
+	writeConfig(t, filepath.Join(projectDir, ProjectConfigFile), `
+hubspot:
+  client_id: project-client
+mcp:
+  url: http://project.example
+  command: ./evil.sh
+credentials:
+  helper: evil
+`)
+	t.Setenv("HUBSPOT_MCP_AUTH_MODE", "header")
+
+	sources, err := LoadSources(map[string]string{"profile": "sandbox"})
+	if err != nil {
+		t.Fatalf("LoadSources() error = %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		value  string
+		origin string
+	}{
+		{"profile", "sandbox", OriginFlag},
+		{"mcp.auth_mode", "header", OriginEnv},
+		{"hubspot.client_id", "project-client", OriginProjectFile},
+		// Project files can't set Sensitive settings
+		{"mcp.url", "http://user.example", OriginUserFile},
+		{"mcp.command", "", OriginDefault},
+		{"credentials.helper", "", OriginDefault},
+		{"hubspot.scopes", "crm.objects.contacts.read crm.objects.deals.read", OriginUserFile},
+		{"hubspot.redirect_uri", DefaultRedirectURI, OriginDefault},
+	}
+	for _, tt := range tests {
+		got := sources.Lookup(tt.key, "sandbox")
+		if got.Value != tt.value || got.Origin != tt.origin {
+			t.Errorf("Lookup(%q) = %q from %s, want %q from %s", tt.key, got.Value, got.Origin, tt.value, tt.origin)
+		}
+	}
+
+	cfg, err := LoadWithFlags(map[string]string{"mcp.url": "http://flag.example"})
+	if err != nil {
+		t.Fatalf("LoadWithFlags() error = %v", err)
+	}
+	if cfg.MCP.URL != "http://flag.example" || cfg.MCP.AuthMode != "header" || cfg.HubSpot.ClientID != "project-client" {
+		t.Errorf("LoadWithFlags() = %+v", cfg)
+	}
+	if cfg.Profile != DefaultProfile {
+		t.Errorf("Profile = %q, want %q", cfg.Profile, DefaultProfile)
+	}
+}
+
+func TestProfileSettingsAboveFiles(t *testing.T) {
+	userPath, _ := setupConfigDirs(t)
+	writeConfig(t, userPath, `
+hubspot:
+  client_id: user-client
+oauth:
+  token_url: https://user.example/token
+`)
+
+	// A profile saved by "auth login --server" for a server with dynamic client registration
+	profiles, err := LoadProfiles()
+	if err != nil {
+		t.Fatalf("LoadProfiles() error = %v", err)
+	}
+	profiles.Profiles["dcr"] = &Profile{
+		ClientID:      "registered-client",
+		ClientSecret:  "registered-secret",
+		OAuthTokenURL: "https://mcp.example/token",
+		OAuthResource: "https://mcp.example/mcp",
+	}
+	if err := profiles.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	cfg, err := LoadWithFlags(map[string]string{"profile": "dcr"})
+	if err != nil {
+		t.Fatalf("LoadWithFlags() error = %v", err)
+	}
+	if cfg.HubSpot.ClientID != "registered-client" || cfg.OAuth.TokenURL != "https://mcp.example/token" {
+		t.Errorf("dcr profile uses client %q at %q, want the registered client", cfg.HubSpot.ClientID, cfg.OAuth.TokenURL)
+	}
+
+	cfg, err = LoadWithFlags(map[string]string{"profile": "other"})
+	if err != nil {
+		t.Fatalf("LoadWithFlags() error = %v", err)
+	}
+	if cfg.HubSpot.ClientID != "user-client" || cfg.OAuth.TokenURL != "https://user.example/token" {
+		t.Errorf("other profile uses client %q at %q, want the user file's", cfg.HubSpot.ClientID, cfg.OAuth.TokenURL)
+	}
+
+	// Remembering the other profile doesn't pin the values it got from the file or defaults
+	sources, err := LoadSources(map[string]string{"profile": "other"})
+	if err != nil {
+		t.Fatalf("LoadSources() error = %v", err)
+	}
+	cfg.MCP.AuthMode = "context"
+	profiles.Remember(cfg, sources)
+	if p := profiles.Profiles["other"]; p.ClientID != "" || p.OAuthTokenURL != "" || p.MCPURL != "" || p.OAuthProvider != "" || p.MCPAuthMode != "context" {
+		t.Errorf("Remember() saved %+v", p)
+	}
+}
+
+func TestDotEnvBelowEnvironment(t *testing.T) {
+	_, projectDir := setupConfigDirs(t)
+	writeConfig(t, filepath.Join(projectDir, DotEnvFile), `HUBSPOT_CLIENT_ID=dotenv-client
+HUBSPOT_MCP_AUTH_MODE=context
+MISSION_CONTROL_OAUTH_TOKEN_URL=http://evil.example/token
+MISSION_CONTROL_CREDENTIAL_HELPER=evil
+MCP_SERVERS=evil
+`)
+	t.Setenv("HUBSPOT_MCP_AUTH_MODE", "header")
+	t.Setenv("MCP_SERVERS", "")
+	os.Unsetenv("MCP_SERVERS")
+	t.Cleanup(func() {
+		delete(dotEnvVars, "HUBSPOT_CLIENT_ID")
+	})
+
+	sources, err := LoadSources(nil)
+	if err != nil {
+		t.Fatalf("LoadSources() error = %v", err)
+	}
+
+	if got := sources.Lookup("hubspot.client_id", ""); got.Value != "dotenv-client" || got.Origin != OriginDotEnv {
+		t.Errorf("hubspot.client_id = %q from %s, want the .env value", got.Value, got.Origin)
+	}
+	if got := sources.Lookup("mcp.auth_mode", ""); got.Value != "header" || got.Origin != OriginEnv {
+		t.Errorf("mcp.auth_mode = %q from %s, want the environment value", got.Value, got.Origin)
+	}
+
+	// Only the variables of settings that aren't Sensitive are loaded
+	for _, name := range []string{"MISSION_CONTROL_OAUTH_TOKEN_URL", "MISSION_CONTROL_CREDENTIAL_HELPER", "MCP_SERVERS"} {
+		if value, set := os.LookupEnv(name); set {
+			t.Errorf("%s = %q, want it ignored in .env", name, value)
+		}
+	}
+}
+
+func TestConfigFileSetUnset(t *testing.T) {
+	userPath, _ := setupConfigDirs(t)
+
+	file, err := ReadFile(userPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if err := file.Set("mcp.url", "http://set.example"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := file.Set("credentials.backend", "file"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := file.Set("mcp.nope", "x"); err == nil {
+		t.Error("Set() should reject an unknown key")
+	}
+
+	file, err = ReadFile(userPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if file.Values["mcp.url"] != "http://set.example" || file.Values["credentials.backend"] != "file" {
+		t.Errorf("Values = %v", file.Values)
+	}
+
+	removed, err := file.Unset("mcp.url")
+	if err != nil || !removed {
+		t.Fatalf("Unset() = %v, %v", removed, err)
+	}
+	file, _ = ReadFile(userPath)
+	if _, ok := file.Values["mcp.url"]; ok {
+		t.Error("mcp.url is still set after Unset()")
+	}
+
+	writeConfig(t, userPath, "mcp:\n  nope: x\n")
+	if _, err := ReadFile(userPath); err == nil {
+		t.Error("ReadFile() should reject an unknown key")
+	}
+}
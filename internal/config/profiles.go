@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Profile holds the settings of one HubSpot portal. Empty fields fall back to
+// environment variables and defaults.
+type Profile struct {
+	ClientID    string `json:"client_id,omitempty"`
+	Scopes      string `json:"scopes,omitempty"`
+	MCPURL      string `json:"mcp_url,omitempty"`
+	MCPAuthMode string `json:"mcp_auth_mode,omitempty"`
+
+	// OAuthProvider names the OAuth provider preset, e.g. "hubspot-eu1"
+	OAuthProvider string `json:"oauth_provider,omitempty"`
+
+	// Endpoints and client of an authorization server found by discovery
+	// ('auth login --server'); ClientSecret is set only if registration issued one
+	OAuthAuthURL    string `json:"oauth_auth_url,omitempty"`
+	OAuthTokenURL   string `json:"oauth_token_url,omitempty"`
+	OAuthRevokeURL  string `json:"oauth_revoke_url,omitempty"`
+	OAuthClientAuth string `json:"oauth_client_auth,omitempty"`
+	OAuthResource   string `json:"oauth_resource,omitempty"`
+	ClientSecret    string `json:"client_secret,omitempty"`
+}
+
+// ProfileStore is the set of named profiles and the currently selected one,
+// kept in ~/.config/mission-control/profiles.json
+type ProfileStore struct {
+	Current  string              `json:"current,omitempty"`
+	Profiles map[string]*Profile `json:"profiles"`
+
+	path string
+}
+
+// ProfilesPath returns the location of the profile store
+func ProfilesPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "mission-control", "profiles.json"), nil
+}
+
+// LoadProfiles reads the profile store; a missing file yields an empty store
+func LoadProfiles() (*ProfileStore, error) {
+	path, err := ProfilesPath()
+	if err != nil {
+		return nil, err
+	}
+
+	store := &ProfileStore{Profiles: make(map[string]*Profile), path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if store.Profiles == nil {
+		store.Profiles = make(map[string]*Profile)
+	}
+
+	return store, nil
+}
+
+// Save writes the profile store
+func (p *ProfileStore) Save() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profiles: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := os.WriteFile(p.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+
+	return nil
+}
+
+// Names returns the profile names in sorted order
+func (p *ProfileStore) Names() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Active returns the selected profile name, or the default profile when none is selected
+func (p *ProfileStore) Active() string {
+	if p.Current == "" {
+		return DefaultProfile
+	}
+	return p.Current
+}
+
+// Remember records the portal settings of cfg under its profile name, leaving out
+// values that come from the config files in sources or the defaults
+func (p *ProfileStore) Remember(cfg *Config, sources *Sources) {
+	profile := ProfileFromConfig(cfg)
+	sources.Inherited(profile)
+	p.Profiles[cfg.Profile] = profile
+	if p.Current == "" {
+		p.Current = cfg.Profile
+	}
+}
+
+// ProfileFromConfig returns the portal settings of cfg worth saving in a profile
+func ProfileFromConfig(cfg *Config) *Profile {
+	profile := &Profile{
+		ClientID:    cfg.HubSpot.ClientID,
+		Scopes:      cfg.HubSpot.Scopes,
+		MCPURL:      cfg.MCP.URL,
+		MCPAuthMode: cfg.MCP.AuthMode,
+
+		OAuthProvider:   cfg.OAuth.Provider,
+		OAuthAuthURL:    cfg.OAuth.AuthURL,
+		OAuthTokenURL:   cfg.OAuth.TokenURL,
+		OAuthRevokeURL:  cfg.OAuth.RevokeURL,
+		OAuthClientAuth: cfg.OAuth.ClientAuth,
+		OAuthResource:   cfg.OAuth.Resource,
+	}
+	if cfg.OAuth.Resource != "" {
+		// Only a registered client's secret belongs in the profile; a HubSpot
+		// app secret stays in HUBSPOT_CLIENT_SECRET
+		profile.ClientSecret = cfg.HubSpot.ClientSecret
+	}
+	return profile
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the configuration for values that can't work, returning every
+// problem found
+func (c *Config) Validate() error {
+	var errs []error
+	if err := ValidateRedirectURI(c.HubSpot.RedirectURI); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ValidateScopes(c.HubSpot.Scopes); err != nil {
+		errs = append(errs, err)
+	}
+	for _, server := range c.MCP.ServerList() {
+		if err := server.Validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if server.AuthMode != "header" && server.AuthMode != "context" {
+			errs = append(errs, fmt.Errorf("MCP server %q: auth mode must be header or context, got %q", server.Name, server.AuthMode))
+		}
+		if server.Transport == TransportHTTP {
+			if err := validateServerURL(server.URL); err != nil {
+				errs = append(errs, fmt.Errorf("MCP server %q: %w", server.Name, err))
+			}
+		}
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "", "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log.format must be text or json, got %q", c.Log.Format))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateRedirectURI checks that the redirect URI reaches the local callback server:
+// an http URL on a loopback host with an explicit port, the one the server listens on
+func ValidateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("invalid redirect URI %q: %w", uri, err)
+	}
+	if u.Scheme != "http" {
+		return fmt.Errorf("redirect URI %q must start with http:// - the callback server doesn't serve https", uri)
+	}
+	if host := u.Hostname(); host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return fmt.Errorf("redirect URI %q must point at 127.0.0.1, ::1 or localhost", uri)
+		}
+	}
+	if u.Port() == "" {
+		return fmt.Errorf("redirect URI %q has no port, but the callback server listens on %s", uri, DefaultCallbackPort)
+	}
+	if port, err := strconv.Atoi(u.Port()); err != nil || port > 65535 {
+		return fmt.Errorf("redirect URI %q has an invalid port", uri)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("redirect URI %q must not have a fragment", uri)
+	}
+	return nil
+}
+
+// ValidateScopes checks that scopes are space-separated OAuth scope tokens
+func ValidateScopes(scopes string) error {
+	if strings.Contains(scopes, ",") {
+		return fmt.Errorf("scopes must be separated by spaces, not commas: %q", scopes)
+	}
+	for _, scope := range strings.Fields(scopes) {
+		for _, c := range scope {
+			// RFC 6749 section 3.3: printable ASCII except space, " and \
+			if c < 0x21 || c > 0x7e || c == '"' || c == '\\' {
+				return fmt.Errorf("invalid scope %q", scope)
+			}
+		}
+	}
+	return nil
+}
+
+// validateServerURL checks that an MCP server URL is an absolute http(s) URL
+func validateServerURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", raw, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL %q must be an absolute http or https URL", raw)
+	}
+	return nil
+}
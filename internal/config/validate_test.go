@@ -0,0 +1,42 @@
+package config
+
+import "testing"
+
+func TestValidateRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		wantErr bool
+	}{
+		{DefaultRedirectURI, false},
+		{"http://localhost:8400/callback", false},
+		{"http://[::1]:0/oauth/callback", false},
+		{"http://127.0.0.1/oauth/callback", true},
+		{"https://127.0.0.1:8400/oauth/callback", true},
+		{"http://example.com:8400/oauth/callback", true},
+		{"http://127.0.0.1:99999/oauth/callback", true},
+		{"http://127.0.0.1:8400/oauth/callback#done", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateRedirectURI(tt.uri); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRedirectURI(%q) error = %v, wantErr %v", tt.uri, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	tests := []struct {
+		scopes  string
+		wantErr bool
+	}{
+		{DefaultScopes, false},
+		{"", false},
+		{"openid https://example.com/auth/crm", false},
+		{"crm.objects.contacts.read,crm.objects.deals.read", true},
+		{`crm."contacts"`, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateScopes(tt.scopes); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateScopes(%q) error = %v, wantErr %v", tt.scopes, err, tt.wantErr)
+		}
+	}
+}
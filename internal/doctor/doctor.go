@@ -0,0 +1,418 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/launch01/mission-control/internal/config"
+	"github.com/launch01/mission-control/internal/mcp"
+	"github.com/launch01/mission-control/internal/oauth"
+	"github.com/launch01/mission-control/internal/storage"
+)
+
+// Status is the outcome of a check
+type Status string
+
+// Check outcomes
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+const (
+	// checkTimeout bounds each network check
+	checkTimeout = 10 * time.Second
+	// stdioTimeout bounds starting a stdio server, which npx may have to download first
+	stdioTimeout = 2 * time.Minute
+
+	// maxClockSkew fails the clock check; warnClockSkew warns
+	maxClockSkew  = 5 * time.Minute
+	warnClockSkew = 30 * time.Second
+
+	// minNodeMajor is the oldest Node.js release MCP servers on the TypeScript SDK run on
+	minNodeMajor = 18
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	// Hint tells how to fix a warning or failure
+	Hint string `json:"hint,omitempty"`
+}
+
+// Summary counts the checks by outcome
+type Summary struct {
+	Pass int `json:"pass"`
+	Warn int `json:"warn"`
+	Fail int `json:"fail"`
+}
+
+// Report is the outcome of every check, in the order they ran
+type Report struct {
+	Profile string   `json:"profile"`
+	Checks  []Result `json:"checks"`
+	Summary Summary  `json:"summary"`
+}
+
+// Failed reports whether any check failed
+func (r *Report) Failed() bool {
+	return r.Summary.Fail > 0
+}
+
+func (r *Report) add(result Result) {
+	r.Checks = append(r.Checks, result)
+	switch result.Status {
+	case StatusPass:
+		r.Summary.Pass++
+	case StatusWarn:
+		r.Summary.Warn++
+	case StatusFail:
+		r.Summary.Fail++
+	}
+}
+
+func pass(name, format string, v ...interface{}) Result {
+	return Result{Name: name, Status: StatusPass, Message: fmt.Sprintf(format, v...)}
+}
+
+func warn(name, hint, format string, v ...interface{}) Result {
+	return Result{Name: name, Status: StatusWarn, Message: fmt.Sprintf(format, v...), Hint: hint}
+}
+
+func fail(name, hint, format string, v ...interface{}) Result {
+	return Result{Name: name, Status: StatusFail, Message: fmt.Sprintf(format, v...), Hint: hint}
+}
+
+// Run checks the setup described by cfg: the configuration itself, the OAuth
+// callback port, credential storage and the token, every MCP server, and the clock
+func Run(ctx context.Context, cfg *config.Config) *Report {
+	report := &Report{Profile: cfg.Profile}
+
+	report.add(checkConfig(cfg))
+	provider, result := checkProvider(cfg)
+	report.add(result)
+	report.add(checkCallbackPort(cfg))
+
+	store, result := checkCredentials(cfg)
+	report.add(result)
+	token, result := checkToken(cfg, store)
+	report.add(result)
+
+	for _, server := range cfg.MCP.ServerList() {
+		if server.Validate() != nil {
+			continue // reported by the config check
+		}
+		for _, result := range checkServer(ctx, server, token) {
+			report.add(result)
+		}
+	}
+
+	if provider != nil {
+		report.add(checkClock(ctx, provider.TokenURL))
+	}
+	return report
+}
+
+// LoadFailure reports a configuration that couldn't be loaded, so no other check can run
+func LoadFailure(profile string, err error) *Report {
+	report := &Report{Profile: profile}
+	report.add(fail("config", "Check the config files with 'mission-control config list'", "%v", err))
+	return report
+}
+
+// checkConfig validates the configuration values
+func checkConfig(cfg *config.Config) Result {
+	const name = "config"
+	if err := cfg.Validate(); err != nil {
+		return fail(name, "Check where each value comes from with 'mission-control config view --show-origin'",
+			"%s", strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+	return pass(name, "configuration is valid")
+}
+
+// checkProvider builds the OAuth provider and checks that login can use it
+func checkProvider(cfg *config.Config) (*oauth.Provider, Result) {
+	const name = "oauth"
+	provider, err := oauth.NewProvider(cfg)
+	if err != nil {
+		return nil, fail(name, "Fix the oauth.* settings, see 'mission-control config view'", "%v", err)
+	}
+	if cfg.HubSpot.ClientID == "" {
+		return provider, warn(name, "Set hubspot.client_id (HUBSPOT_CLIENT_ID) to log in with OAuth; private app tokens work without it",
+			"no OAuth client ID configured for provider %s", provider.Name)
+	}
+	return provider, pass(name, "provider %s, client %s", provider.Name, cfg.HubSpot.ClientID)
+}
+
+// checkCallbackPort checks that the login callback server can bind the redirect URI's port
+func checkCallbackPort(cfg *config.Config) Result {
+	const name = "callback port"
+	if config.ValidateRedirectURI(cfg.HubSpot.RedirectURI) != nil {
+		return warn(name, "Fix hubspot.redirect_uri first", "skipped: the redirect URI is invalid")
+	}
+
+	u, _ := url.Parse(cfg.HubSpot.RedirectURI)
+	if u.Port() == "0" {
+		return pass(name, "a free port is chosen at login")
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		host = "127.0.0.1"
+	}
+	addr := net.JoinHostPort(host, u.Port())
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return warn(name, fmt.Sprintf("Stop the process using port %s (e.g. find it with 'lsof -i :%s'), or move hubspot.redirect_uri and the app's redirect URL to another port", u.Port(), u.Port()),
+			"%s is in use, so 'auth login' can't receive the callback", addr)
+	}
+	listener.Close()
+	return pass(name, "%s is free for the login callback", addr)
+}
+
+// checkCredentials checks the credential backend, including whether the OS keyring works
+func checkCredentials(cfg *config.Config) (*storage.TokenStorage, Result) {
+	const name = "credentials"
+	backend, err := storage.SelectBackend()
+	if err != nil {
+		return nil, fail(name, "Fix credentials.backend ("+storage.CredentialBackendEnv+")", "%v", err)
+	}
+
+	store, err := storage.NewProfileTokenStorage(cfg.Profile)
+	if err != nil {
+		return nil, fail(name, "Fix credentials.backend ("+storage.CredentialBackendEnv+")", "%v", err)
+	}
+	selected := os.Getenv(storage.CredentialBackendEnv)
+	if backend.Name() == storage.BackendFile && (selected == "" || selected == storage.BackendAuto) {
+		return store, warn(name, "Unlock or install an OS keyring service (e.g. gnome-keyring), or set credentials.backend to file to make this the default",
+			"OS keyring is not available; tokens are kept in encrypted files (%s)", backend.Location(cfg.Profile))
+	}
+	return store, pass(name, "%s backend (%s)", backend.Name(), backend.Location(cfg.Profile))
+}
+
+// checkToken checks the profile's token, returning it when it can be used as is
+func checkToken(cfg *config.Config, store *storage.TokenStorage) (*storage.Token, Result) {
+	const name = "token"
+	if store == nil {
+		return nil, warn(name, "Fix the credential backend first", "skipped: no credential backend")
+	}
+
+	loginHint := "Run 'mission-control auth login', or 'mission-control auth set-token' for a private app token"
+	token, err := store.LoadToken()
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fail(name, loginHint, "no token stored for profile %s", cfg.Profile)
+	}
+	if err != nil {
+		return nil, fail(name, loginHint, "%v", err)
+	}
+
+	if token.IsPrivateApp() {
+		return token, pass(name, "private app token for profile %s", cfg.Profile)
+	}
+	if token.IsExpired() {
+		if token.RefreshToken == "" {
+			return nil, fail(name, loginHint, "token expired at %s and has no refresh token", token.ExpiresAt.Format(time.RFC3339))
+		}
+		return nil, warn(name, "Any command using the token refreshes it, e.g. 'mission-control tools list'",
+			"token expired at %s", token.ExpiresAt.Format(time.RFC3339))
+	}
+	if token.ClientID != "" && cfg.HubSpot.ClientID != "" && token.ClientID != cfg.HubSpot.ClientID {
+		return token, warn(name, loginHint+" with the configured client",
+			"token was issued to client %s, but client %s is configured; refreshing it will fail", token.ClientID, cfg.HubSpot.ClientID)
+	}
+	if len(token.Scopes) > 0 {
+		if missing := oauth.MissingScopes(cfg.HubSpot.Scopes, token.Scopes); len(missing) > 0 {
+			return token, warn(name, "Run 'mission-control auth login --add-scopes "+strings.Join(missing, ",")+"'",
+				"token lacks configured scopes: %s", strings.Join(missing, " "))
+		}
+	}
+	return token, pass(name, "OAuth token valid until %s", token.ExpiresAt.Format(time.RFC3339))
+}
+
+// checkServer checks that an MCP server can be reached and completes the initialize
+// handshake. token is the profile's usable token, if any.
+func checkServer(ctx context.Context, server config.MCPServerConfig, token *storage.Token) []Result {
+	prefix := "mcp " + server.Name
+	var results []Result
+	var client *mcp.Client
+	timeout := checkTimeout
+
+	switch server.Transport {
+	case config.TransportStdio:
+		result := checkCommand(prefix+" command", server.Command)
+		results = append(results, result)
+		if result.Status == StatusFail {
+			return results
+		}
+		client = mcp.NewClientWithTransport(mcp.NewStdioTransport(mcp.StdioOptions{
+			Command:  server.Command,
+			Args:     server.Args,
+			TokenEnv: server.TokenEnv,
+		}))
+		timeout = stdioTimeout
+	default:
+		result := checkReachable(ctx, prefix+" reachable", server.URL)
+		results = append(results, result)
+		if result.Status == StatusFail {
+			return results
+		}
+		client = mcp.NewClient(server.URL, server.AuthMode)
+	}
+	defer client.Close()
+
+	switch {
+	case server.TokenSource == config.TokenSourceOAuth && token != nil:
+		client.SetToken(token.AccessToken)
+	case strings.HasPrefix(server.TokenSource, config.TokenSourceEnvPrefix):
+		client.SetToken(os.Getenv(strings.TrimPrefix(server.TokenSource, config.TokenSourceEnvPrefix)))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return append(results, checkHandshake(ctx, prefix+" handshake", server, client))
+}
+
+// checkReachable checks that the server's host accepts connections
+func checkReachable(ctx context.Context, name, serverURL string) Result {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return fail(name, "Fix the server URL", "invalid URL %q: %v", serverURL, err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+
+	dialer := net.Dialer{Timeout: checkTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fail(name, "Start the MCP server (e.g. 'npx @hubspot/mcp-server'), or point mcp.url at a running one",
+			"can't connect to %s: %v", addr, err)
+	}
+	conn.Close()
+	return pass(name, "%s accepts connections", addr)
+}
+
+// checkHandshake performs the MCP initialize handshake. The stdio transport runs it
+// when it starts the server, so stdio servers are asked for their tools instead.
+func checkHandshake(ctx context.Context, name string, server config.MCPServerConfig, client *mcp.Client) Result {
+	var message string
+	var err error
+	if server.Transport == config.TransportStdio {
+		var tools []mcp.Tool
+		if tools, err = client.ListTools(ctx); err == nil {
+			message = fmt.Sprintf("server started and lists %d tools", len(tools))
+		}
+	} else {
+		var result *mcp.InitializeResult
+		if result, err = client.Initialize(ctx); err == nil {
+			message = fmt.Sprintf("protocol %s", result.ProtocolVersion)
+			if result.ServerInfo.Name != "" {
+				message = fmt.Sprintf("%s %s, protocol %s", result.ServerInfo.Name, result.ServerInfo.Version, result.ProtocolVersion)
+			}
+		}
+	}
+
+	switch {
+	case err == nil:
+		return pass(name, "%s", message)
+	case errors.Is(err, mcp.ErrUnauthorized), errors.Is(err, mcp.ErrForbidden):
+		return warn(name, "Run 'mission-control auth login', or check the server's token source", "server rejected the token: %v", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return fail(name, "Check that the server speaks MCP and isn't stuck; run with --log-level debug to see the exchange", "no reply within the timeout")
+	}
+	return fail(name, "Check that the server speaks MCP; run with --log-level debug to see the exchange", "%v", err)
+}
+
+// nodeVersion matches the major version printed by 'node --version', e.g. v20.11.1
+var nodeVersion = regexp.MustCompile(`^v(\d+)\.`)
+
+// checkCommand checks that a stdio server's command is installed, and for Node.js
+// launchers that Node.js is recent enough
+func checkCommand(name, command string) Result {
+	nodeHint := fmt.Sprintf("Install Node.js %d or later, which includes npx, from https://nodejs.org", minNodeMajor)
+	if _, err := exec.LookPath(command); err != nil {
+		base := filepath.Base(command)
+		if base == "npx" || base == "node" || base == "npm" {
+			return fail(name, nodeHint, "%s not found in PATH", command)
+		}
+		return fail(name, "Install it, or fix the server's command", "%s not found in PATH", command)
+	}
+
+	switch filepath.Base(command) {
+	case "npx", "node", "npm":
+	default:
+		return pass(name, "%s found", command)
+	}
+
+	output, err := exec.Command("node", "--version").Output()
+	if err != nil {
+		return fail(name, nodeHint, "%s found, but node isn't: %v", command, err)
+	}
+	version := strings.TrimSpace(string(output))
+	if m := nodeVersion.FindStringSubmatch(version); m != nil {
+		if major, _ := strconv.Atoi(m[1]); major < minNodeMajor {
+			return warn(name, nodeHint, "Node.js %s is older than %d", version, minNodeMajor)
+		}
+	}
+	return pass(name, "%s found, Node.js %s", command, version)
+}
+
+// checkClock compares the local clock with the Date header of the OAuth token
+// endpoint; token expiry checks depend on it
+func checkClock(ctx context.Context, tokenURL string) Result {
+	const name = "clock"
+	hint := "Enable time synchronization (e.g. 'timedatectl set-ntp true' or your OS's date settings)"
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, tokenURL, nil)
+	if err != nil {
+		return warn(name, "Fix the OAuth token URL", "can't check the clock: %v", err)
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return warn(name, "Check network access to "+req.URL.Host, "can't check the clock: %v", err)
+	}
+	resp.Body.Close()
+	// Compare with the middle of the round trip
+	local := start.Add(time.Since(start) / 2)
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return warn(name, "", "can't check the clock: %s sent no Date header", req.URL.Host)
+	}
+
+	skew := local.Sub(date).Round(time.Second)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs > maxClockSkew:
+		return fail(name, hint, "local clock is %s off from %s; tokens will look valid or expired at the wrong times", skew, req.URL.Host)
+	case abs > warnClockSkew:
+		return warn(name, hint, "local clock is %s off from %s", skew, req.URL.Host)
+	}
+	return pass(name, "local clock is within %s of %s", warnClockSkew, req.URL.Host)
+}
@@ -0,0 +1,151 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/launch01/mission-control/internal/config"
+	"github.com/launch01/mission-control/internal/mcp"
+	"github.com/launch01/mission-control/internal/storage"
+)
+
+// newTestServer serves the MCP initialize handshake, requiring token, and sends a
+// Date header skew away from the local clock
+func newTestServer(t *testing.T, token string, skew time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodHead {
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var request mcp.JSONRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		if request.Method != "initialize" {
+			t.Errorf("method = %q, want initialize", request.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      request.ID,
+			"result": map[string]interface{}{
+				"protocolVersion": mcp.ProtocolVersion,
+				"serverInfo":      map[string]string{"name": "test-server", "version": "1.2.3"},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testConfig(serverURL string) *config.Config {
+	return &config.Config{
+		Profile: config.DefaultProfile,
+		HubSpot: config.HubSpotConfig{
+			ClientID:    "client-123",
+			RedirectURI: "http://127.0.0.1:0/oauth/callback",
+			Scopes:      "crm.objects.contacts.read",
+			APIBaseURL:  serverURL,
+		},
+		MCP: config.MCPConfig{URL: serverURL, AuthMode: "header"},
+	}
+}
+
+func statuses(report *Report) map[string]Status {
+	got := map[string]Status{}
+	for _, check := range report.Checks {
+		got[check.Name] = check.Status
+	}
+	return got
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(storage.CredentialBackendEnv, storage.BackendFile)
+	t.Setenv(storage.CredentialHelperEnv, "")
+
+	server := newTestServer(t, "access-123", 0)
+	cfg := testConfig(server.URL)
+
+	report := Run(context.Background(), cfg)
+	got := statuses(report)
+	if got["token"] != StatusFail || got["mcp hubspot handshake"] != StatusWarn {
+		t.Errorf("without a token: %+v", report.Checks)
+	}
+	if !report.Failed() {
+		t.Error("Failed() = false without a token")
+	}
+
+	store, err := storage.NewProfileTokenStorage(cfg.Profile)
+	if err != nil {
+		t.Fatalf("NewProfileTokenStorage() error = %v", err)
+	}
+	err = store.SaveToken(&storage.Token{
+		AccessToken:  "access-123",
+		RefreshToken: "refresh-123",
+		ExpiresAt:    time.Now().Add(time.Hour),
+		Type:         storage.TokenTypeOAuth,
+		Scopes:       []string{"crm.objects.contacts.read"},
+		ClientID:     "client-123",
+	})
+	if err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	report = Run(context.Background(), cfg)
+	for _, check := range report.Checks {
+		if check.Status != StatusPass {
+			t.Errorf("check %s = %s: %s", check.Name, check.Status, check.Message)
+		}
+	}
+	if report.Summary.Pass != len(report.Checks) {
+		t.Errorf("Summary = %+v", report.Summary)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	cfg := testConfig("http://127.0.0.1:3333")
+	cfg.HubSpot.RedirectURI = "http://127.0.0.1/oauth/callback"
+	cfg.HubSpot.Scopes = "crm.objects.contacts.read,crm.objects.deals.read"
+
+	result := checkConfig(cfg)
+	if result.Status != StatusFail || result.Hint == "" {
+		t.Errorf("checkConfig() = %+v", result)
+	}
+}
+
+func TestCheckClock(t *testing.T) {
+	tests := []struct {
+		skew time.Duration
+		want Status
+	}{
+		{0, StatusPass},
+		{2 * time.Minute, StatusWarn},
+		{-10 * time.Minute, StatusFail},
+	}
+	for _, tt := range tests {
+		server := newTestServer(t, "", tt.skew)
+		if got := checkClock(context.Background(), server.URL); got.Status != tt.want {
+			t.Errorf("checkClock() with skew %s = %+v, want %s", tt.skew, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	if got := checkCommand("mcp test command", "mission-control-no-such-command"); got.Status != StatusFail {
+		t.Errorf("checkCommand() = %+v, want a failure", got)
+	}
+	if got := checkCommand("mcp test command", "sh"); got.Status != StatusPass {
+		t.Errorf("checkCommand(sh) = %+v", got)
+	}
+}
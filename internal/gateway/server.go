@@ -0,0 +1,413 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/launch01/mission-control/internal/agent"
+	"github.com/launch01/mission-control/internal/logging"
+	"github.com/launch01/mission-control/internal/mcp"
+	"github.com/launch01/mission-control/internal/policy"
+)
+
+// Backend is the upstream the gateway re-exposes; *agent.Agent implements it
+type Backend interface {
+	ListTools(ctx context.Context) ([]mcp.Tool, error)
+	CallTool(ctx context.Context, name string, args map[string]interface{}) (json.RawMessage, error)
+	ListResources(ctx context.Context) ([]mcp.Resource, error)
+	ListResourceTemplates(ctx context.Context) ([]mcp.ResourceTemplate, error)
+	ReadResource(ctx context.Context, uri string) (json.RawMessage, error)
+	ListPrompts(ctx context.Context) ([]mcp.Prompt, error)
+	GetPrompt(ctx context.Context, name string, args map[string]string) (json.RawMessage, error)
+}
+
+// Server speaks MCP to downstream clients and forwards every request to the backend,
+// so downstream clients never see upstream credentials
+type Server struct {
+	backend Backend
+	// hosts are the non-loopback host names ServeHTTP accepts besides loopback ones
+	hosts map[string]bool
+}
+
+// maxRequestSize bounds the body of an HTTP request, like the line length over stdio
+const maxRequestSize = 16 * 1024 * 1024
+
+// request is an incoming JSON-RPC message; the ID is kept raw so it is echoed unchanged
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is an outgoing JSON-RPC message
+type response struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Result  interface{}       `json:"result,omitempty"`
+	Error   *mcp.JSONRPCError `json:"error,omitempty"`
+}
+
+// NewServer creates a gateway server in front of the backend
+func NewServer(backend Backend) *Server {
+	return &Server{backend: backend, hosts: map[string]bool{}}
+}
+
+// AllowHost lets ServeHTTP accept requests for a non-loopback host, when the gateway
+// is deliberately served on one; "*" accepts any host
+func (s *Server) AllowHost(host string) {
+	s.hosts[strings.ToLower(host)] = true
+}
+
+// ServeStdio reads newline-delimited JSON-RPC messages from in and writes replies to out
+// until in is closed or ctx is canceled. Requests are handled concurrently.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		payload := append([]byte(nil), line...)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply := s.Handle(ctx, payload)
+			if reply == nil {
+				return
+			}
+
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			out.Write(append(reply, '\n'))
+		}()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ServeHTTP accepts JSON-RPC messages POSTed by downstream clients. The gateway acts
+// with the user's credentials, so requests from web pages are refused: the Host must be
+// loopback, which defeats DNS rebinding, any Origin must be loopback too, and the body
+// must be JSON, which a page can't send cross-origin without a preflight.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.allowedHost(r.Host) {
+		http.Error(w, "host not allowed", http.StatusForbidden)
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && !s.allowedOrigin(origin) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	reply := s.Handle(r.Context(), payload)
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
+
+// allowedHost reports whether host, with or without a port, is loopback or allowed
+func (s *Server) allowedHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if host == "localhost" || s.hosts[host] || s.hosts["*"] {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// allowedOrigin reports whether a browser origin is on an allowed host
+func (s *Server) allowedOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return s.allowedHost(u.Host)
+}
+
+// Handle processes a single message or a batch and returns the encoded reply,
+// or nil when the payload only contained notifications
+func (s *Server) Handle(ctx context.Context, payload []byte) []byte {
+	payload = bytes.TrimSpace(payload)
+
+	if len(payload) > 0 && payload[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(payload, &batch); err != nil || len(batch) == 0 {
+			return encode(errorResponse(nil, mcp.CodeInvalidRequest, "invalid batch"))
+		}
+
+		var replies []*response
+		for _, item := range batch {
+			if reply := s.handleMessage(ctx, item); reply != nil {
+				replies = append(replies, reply)
+			}
+		}
+		if len(replies) == 0 {
+			return nil
+		}
+		return encode(replies)
+	}
+
+	reply := s.handleMessage(ctx, payload)
+	if reply == nil {
+		return nil
+	}
+	return encode(reply)
+}
+
+// handleMessage dispatches one JSON-RPC message; notifications get no reply
+func (s *Server) handleMessage(ctx context.Context, payload []byte) *response {
+	var req request
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return errorResponse(nil, mcp.CodeParseError, "parse error")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, mcp.CodeInvalidRequest, "invalid request")
+	}
+
+	if len(req.ID) > 0 {
+		ctx = logging.NewContext(ctx, "request_id", string(req.ID))
+	}
+	start := time.Now()
+	result, err := s.dispatch(ctx, req)
+	log := logging.FromContext(ctx).With("method", req.Method, "duration", time.Since(start))
+	if err != nil {
+		log.Info("Gateway request failed", "error", err)
+	} else {
+		log.Info("Gateway request")
+	}
+
+	if len(req.ID) == 0 {
+		return nil
+	}
+	if err != nil {
+		return toErrorResponse(req.ID, err)
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// dispatch runs the method named by the request
+func (s *Server) dispatch(ctx context.Context, req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := params.ProtocolVersion
+		if version == "" {
+			version = mcp.ProtocolVersion
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities": map[string]interface{}{
+				"tools":     map[string]interface{}{},
+				"resources": map[string]interface{}{},
+				"prompts":   map[string]interface{}{},
+			},
+			"serverInfo": map[string]interface{}{
+				"name":    "mission-control",
+				"version": "1.0.0",
+			},
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		tools, err := s.backend.ListTools(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if tools == nil {
+			tools = []mcp.Tool{}
+		}
+		return map[string]interface{}{"tools": tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+			return nil, invalidParams("tools/call requires a tool name")
+		}
+		result, err := s.backend.CallTool(ctx, params.Name, params.Arguments)
+		var scopeErr *agent.ScopeError
+		if errors.As(err, &scopeErr) {
+			// Checked before ToolError, which it may wrap, so the client sees how to grant the scope
+			return map[string]interface{}{"content": scopeErrorContent(scopeErr), "isError": true}, nil
+		}
+		var toolErr *mcp.ToolError
+		if errors.As(err, &toolErr) {
+			// Tool failures are results, not protocol errors
+			return map[string]interface{}{"content": toolErr.Content, "isError": true}, nil
+		}
+		var deniedErr *policy.DeniedError
+		if errors.As(err, &deniedErr) {
+			// Report denials as tool results so the model sees why the call was refused
+			return map[string]interface{}{
+				"content": []map[string]string{{"type": "text", "text": deniedErr.Error()}},
+				"isError": true,
+			}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+
+	case "resources/list":
+		resources, err := s.backend.ListResources(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if resources == nil {
+			resources = []mcp.Resource{}
+		}
+		return map[string]interface{}{"resources": resources}, nil
+
+	case "resources/templates/list":
+		templates, err := s.backend.ListResourceTemplates(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if templates == nil {
+			templates = []mcp.ResourceTemplate{}
+		}
+		return map[string]interface{}{"resourceTemplates": templates}, nil
+
+	case "resources/read":
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+			return nil, invalidParams("resources/read requires a uri")
+		}
+		return s.backend.ReadResource(ctx, params.URI)
+
+	case "prompts/list":
+		prompts, err := s.backend.ListPrompts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if prompts == nil {
+			prompts = []mcp.Prompt{}
+		}
+		return map[string]interface{}{"prompts": prompts}, nil
+
+	case "prompts/get":
+		var params struct {
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+			return nil, invalidParams("prompts/get requires a prompt name")
+		}
+		return s.backend.GetPrompt(ctx, params.Name, params.Arguments)
+
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	}
+
+	return nil, &mcp.RPCError{Code: mcp.CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+// scopeErrorContent returns the content of a tool call rejected for a missing scope:
+// the tool's own content, if any, followed by the hint to grant the scope
+func scopeErrorContent(err *agent.ScopeError) []interface{} {
+	var content []interface{}
+	var toolErr *mcp.ToolError
+	if errors.As(err.Err, &toolErr) {
+		json.Unmarshal(toolErr.Content, &content)
+	}
+	return append(content, map[string]string{"type": "text", "text": err.Error()})
+}
+
+// invalidParams builds a JSON-RPC invalid params error
+func invalidParams(message string) error {
+	return &mcp.RPCError{Code: mcp.CodeInvalidParams, Message: message}
+}
+
+// toErrorResponse converts an error into a JSON-RPC error, passing upstream errors through unchanged
+func toErrorResponse(id json.RawMessage, err error) *response {
+	var rpcErr *mcp.RPCError
+	if errors.As(err, &rpcErr) {
+		return &response{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &mcp.JSONRPCError{Code: rpcErr.Code, Message: rpcErr.Message, Data: rpcErr.Data},
+		}
+	}
+	return errorResponse(id, mcp.CodeInternalError, err.Error())
+}
+
+// errorResponse builds a JSON-RPC error reply
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &mcp.JSONRPCError{Code: code, Message: message},
+	}
+}
+
+// encode marshals a reply; replies are built from marshalable values only
+func encode(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		logging.Error("Failed to encode gateway response: %v", err)
+		data, _ = json.Marshal(errorResponse(nil, mcp.CodeInternalError, "failed to encode response"))
+	}
+	return data
+}
@@ -0,0 +1,216 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/launch01/mission-control/internal/agent"
+	"github.com/launch01/mission-control/internal/mcp"
+)
+
+type fakeBackend struct {
+	calls []string
+}
+
+func (b *fakeBackend) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	return []mcp.Tool{{Name: "hubspot/search_contacts", Description: "Search contacts"}}, nil
+}
+
+func (b *fakeBackend) CallTool(ctx context.Context, name string, args map[string]interface{}) (json.RawMessage, error) {
+	b.calls = append(b.calls, name)
+	switch name {
+	case "hubspot/broken":
+		return nil, &mcp.ToolError{Tool: name, Content: json.RawMessage(`[{"type":"text","text":"boom"}]`)}
+	case "hubspot/no_scope":
+		return nil, &agent.ScopeError{
+			Scopes: []string{"crm.objects.deals.read"},
+			Err:    &mcp.ToolError{Tool: name, Content: json.RawMessage(`[{"type":"text","text":"MISSING_SCOPES"}]`)},
+		}
+	case "hubspot/bad_args":
+		return nil, &mcp.RPCError{Code: mcp.CodeInvalidParams, Message: "Invalid params", Data: json.RawMessage(`{"field":"limit"}`)}
+	}
+	return json.RawMessage(`{"content":[{"type":"text","text":"ok"}]}`), nil
+}
+
+func (b *fakeBackend) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) ListResourceTemplates(ctx context.Context) ([]mcp.ResourceTemplate, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) ReadResource(ctx context.Context, uri string) (json.RawMessage, error) {
+	return json.RawMessage(`{"contents":[]}`), nil
+}
+
+func (b *fakeBackend) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) GetPrompt(ctx context.Context, name string, args map[string]string) (json.RawMessage, error) {
+	return json.RawMessage(`{"messages":[]}`), nil
+}
+
+func TestHandleToolsList(t *testing.T) {
+	server := NewServer(&fakeBackend{})
+
+	reply := server.Handle(context.Background(), []byte(`{"jsonrpc":"2.0","id":7,"method":"tools/list"}`))
+
+	var resp struct {
+		ID     int `json:"id"`
+		Result struct {
+			Tools []mcp.Tool `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		t.Fatalf("Failed to unmarshal reply: %v", err)
+	}
+
+	if resp.ID != 7 {
+		t.Errorf("ID = %d, want 7 (numeric IDs must be echoed unchanged)", resp.ID)
+	}
+	if len(resp.Result.Tools) != 1 || resp.Result.Tools[0].Name != "hubspot/search_contacts" {
+		t.Errorf("Tools = %+v", resp.Result.Tools)
+	}
+}
+
+func TestHandleToolErrors(t *testing.T) {
+	server := NewServer(&fakeBackend{})
+
+	reply := server.Handle(context.Background(), []byte(`[
+		{"jsonrpc":"2.0","id":"a","method":"tools/call","params":{"name":"hubspot/broken"}},
+		{"jsonrpc":"2.0","id":"b","method":"tools/call","params":{"name":"hubspot/bad_args"}},
+		{"jsonrpc":"2.0","method":"notifications/initialized"}
+	]`))
+
+	var responses []struct {
+		ID     string            `json:"id"`
+		Result json.RawMessage   `json:"result"`
+		Error  *mcp.JSONRPCError `json:"error"`
+	}
+	if err := json.Unmarshal(reply, &responses); err != nil {
+		t.Fatalf("Failed to unmarshal reply: %v", err)
+	}
+
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 responses (no reply to notifications), got %d", len(responses))
+	}
+
+	// Tool failures are reported as isError results
+	if !strings.Contains(string(responses[0].Result), `"isError":true`) {
+		t.Errorf("Expected isError result, got %s", responses[0].Result)
+	}
+
+	// Upstream JSON-RPC errors keep their code and data
+	if responses[1].Error == nil || responses[1].Error.Code != mcp.CodeInvalidParams || string(responses[1].Error.Data) != `{"field":"limit"}` {
+		t.Errorf("Unexpected error %+v", responses[1].Error)
+	}
+}
+
+func TestHandleMissingScope(t *testing.T) {
+	server := NewServer(&fakeBackend{})
+
+	reply := server.Handle(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"hubspot/no_scope"}}`))
+
+	var resp struct {
+		Result struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+			IsError bool `json:"isError"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		t.Fatalf("Failed to unmarshal reply: %v", err)
+	}
+
+	content := resp.Result.Content
+	if !resp.Result.IsError || len(content) != 2 || content[0].Text != "MISSING_SCOPES" {
+		t.Fatalf("Expected the tool's content and a hint, got %s", reply)
+	}
+	if !strings.Contains(content[1].Text, "auth login --add-scopes crm.objects.deals.read") {
+		t.Errorf("Hint = %q", content[1].Text)
+	}
+}
+
+func TestServeStdio(t *testing.T) {
+	backend := &fakeBackend{}
+	server := NewServer(backend)
+
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}
+{"jsonrpc":"2.0","method":"notifications/initialized"}
+{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"hubspot/search_contacts","arguments":{}}}
+`)
+	var out bytes.Buffer
+
+	if err := server.ServeStdio(context.Background(), in, &out); err != nil {
+		t.Fatalf("ServeStdio() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 replies, got %d: %s", len(lines), out.String())
+	}
+	if !strings.Contains(out.String(), `"protocolVersion":"2025-03-26"`) {
+		t.Errorf("Expected negotiated protocol version in %s", out.String())
+	}
+	if len(backend.calls) != 1 || backend.calls[0] != "hubspot/search_contacts" {
+		t.Errorf("Backend calls = %v", backend.calls)
+	}
+}
+
+func TestServeHTTPRejectsForeignRequests(t *testing.T) {
+	const call = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"hubspot/search_contacts"}}`
+	tests := []struct {
+		name        string
+		host        string
+		origin      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"local client", "127.0.0.1:3334", "", "application/json", call, http.StatusOK},
+		{"localhost with charset", "localhost:3334", "http://localhost:8080", "application/json; charset=utf-8", call, http.StatusOK},
+		{"web page", "127.0.0.1:3334", "https://evil.example", "application/json", call, http.StatusForbidden},
+		{"DNS rebinding", "evil.example:3334", "", "application/json", call, http.StatusForbidden},
+		{"simple CORS request", "127.0.0.1:3334", "", "text/plain", call, http.StatusUnsupportedMediaType},
+		{"oversized body", "127.0.0.1:3334", "", "application/json", strings.Repeat(" ", maxRequestSize+1), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{}
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Host = tt.host
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			NewServer(backend).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if called := len(backend.calls) > 0; called != (tt.want == http.StatusOK) {
+				t.Errorf("backend calls = %v", backend.calls)
+			}
+		})
+	}
+
+	server := NewServer(&fakeBackend{})
+	server.AllowHost("gateway.internal")
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(call))
+	req.Host = "gateway.internal:3334"
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("allowed host status = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,58 @@
+// Package lockfile provides an exclusive lock shared between processes through a file.
+package lockfile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pollInterval is how often a held lock is retried
+const pollInterval = 50 * time.Millisecond
+
+// Lock is an exclusive lock on a file, held until Release
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// Acquire takes the lock at path, waiting until it is free or ctx is done.
+// The lock is released automatically if the process exits.
+func Acquire(ctx context.Context, path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	for {
+		file, err := tryLock(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if file != nil {
+			return &Lock{path: path, file: file}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for lock %s: %w", path, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Path returns the lock file's path
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// Release gives up the lock
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := unlock(l.path, l.file)
+	l.file = nil
+	return err
+}
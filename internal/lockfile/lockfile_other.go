@@ -0,0 +1,36 @@
+//go:build !unix
+
+package lockfile
+
+import (
+	"os"
+	"time"
+)
+
+// staleAfter is the age at which a lock file left behind by a crashed process is broken
+const staleAfter = 2 * time.Minute
+
+// tryLock creates path exclusively; it returns a nil file if another process holds it.
+// Without flock a crashed holder leaves the file behind, so old lock files are removed.
+func tryLock(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		return file, nil
+	}
+	if !os.IsExist(err) {
+		return nil, err
+	}
+
+	if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleAfter {
+		os.Remove(path)
+	}
+	return nil, nil
+}
+
+func unlock(path string, file *os.File) error {
+	err := file.Close()
+	if removeErr := os.Remove(path); err == nil {
+		err = removeErr
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package lockfile
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireIsExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "test.lock")
+
+	first, err := Acquire(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := Acquire(ctx, path); err == nil {
+		t.Fatal("second Acquire() should wait while the lock is held")
+	}
+
+	acquired := make(chan *Lock)
+	go func() {
+		lock, err := Acquire(context.Background(), path)
+		if err != nil {
+			t.Errorf("Acquire() after release error = %v", err)
+		}
+		acquired <- lock
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	select {
+	case lock := <-acquired:
+		lock.Release()
+	case <-time.After(2 * time.Second):
+		t.Fatal("Acquire() did not get the lock after it was released")
+	}
+}
@@ -1,26 +1,156 @@
 package logging
 
 import (
+	"context"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configures the logger
+type Options struct {
+	// Level is debug, info (default), warn or error
+	Level string
+	// Format is FormatText (default) or FormatJSON
+	Format string
+	// File receives the logs instead of stderr, rotated by size
+	File string
+	// MaxSize is the size in bytes at which the log file is rotated (default 10 MiB)
+	MaxSize int64
+	// MaxBackups is the number of rotated files kept (default 3)
+	MaxBackups int
+}
+
+const (
+	defaultMaxSize    = 10 << 20
+	defaultMaxBackups = 3
 )
 
 var (
-	InfoLogger  *log.Logger
-	ErrorLogger *log.Logger
-	DebugLogger *log.Logger
+	mu     sync.Mutex
+	logger *slog.Logger
+	level  = new(slog.LevelVar)
+	format = FormatText
+	output io.Writer
+	file   *rotatingFile
 )
 
 func init() {
-	InfoLogger = log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime)
-	ErrorLogger = log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
-	DebugLogger = log.New(os.Stdout, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
+	// DEBUG=true predates --log-level and still enables debug logs
+	if os.Getenv("DEBUG") == "true" {
+		level.Set(slog.LevelDebug)
+	}
+	output = os.Stderr
+	logger = newLogger()
+}
+
+// Configure sets the level, format and destination of the logs. Diagnostic logs
+// always go to stderr or the log file, never stdout, which carries command output.
+func Configure(opts Options) error {
+	lvl := level.Level()
+	if opts.Level != "" {
+		if err := lvl.UnmarshalText([]byte(opts.Level)); err != nil {
+			return fmt.Errorf("invalid log level %q (use debug, info, warn or error)", opts.Level)
+		}
+	}
+
+	f := strings.ToLower(opts.Format)
+	switch f {
+	case "":
+		f = format
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("invalid log format %q (use %s or %s)", opts.Format, FormatText, FormatJSON)
+	}
+
+	var w io.Writer = os.Stderr
+	var rotating *rotatingFile
+	if opts.File != "" {
+		if opts.MaxSize <= 0 {
+			opts.MaxSize = defaultMaxSize
+		}
+		if opts.MaxBackups <= 0 {
+			opts.MaxBackups = defaultMaxBackups
+		}
+		var err error
+		if rotating, err = openRotatingFile(opts.File, opts.MaxSize, opts.MaxBackups); err != nil {
+			return err
+		}
+		w = rotating
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	level.Set(lvl)
+	format, output, file = f, w, rotating
+	logger = newLogger()
+	return nil
+}
 
-	// Disable debug by default
-	if os.Getenv("DEBUG") != "true" {
-		DebugLogger.SetOutput(io.Discard)
+// newLogger builds the logger for the current settings; callers hold mu
+func newLogger() *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: level.Level() <= slog.LevelDebug,
 	}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(output, opts))
+	}
+	return slog.New(slog.NewTextHandler(output, opts))
+}
+
+// Logger returns the configured logger
+func Logger() *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	return logger
+}
+
+// With adds attributes, e.g. the profile, to every later log record
+func With(args ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	logger = logger.With(args...)
+}
+
+type contextKey struct{}
+
+// NewContext returns a context whose logs carry the given attributes, e.g. a request ID
+func NewContext(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, contextKey{}, FromContext(ctx).With(args...))
+}
+
+// FromContext returns the logger carried by ctx, or the configured logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return Logger()
+}
+
+// SetOutput sends the logs to w unless a log file is configured
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		return
+	}
+	output = w
+	logger = newLogger()
 }
 
 // RedactSensitive redacts sensitive information from strings
@@ -32,22 +162,35 @@ func RedactSensitive(s string) string {
 	return "***"
 }
 
+// logf logs a printf-style message, attributed to the caller of the wrapper
+func logf(lvl slog.Level, format string, v ...interface{}) {
+	l := Logger()
+	if !l.Enabled(context.Background(), lvl) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), lvl, fmt.Sprintf(format, v...), pcs[0])
+	l.Handler().Handle(context.Background(), r)
+}
+
 // Info logs an info message
 func Info(format string, v ...interface{}) {
-	InfoLogger.Printf(format, v...)
+	logf(slog.LevelInfo, format, v...)
 }
 
 // Error logs an error message
 func Error(format string, v ...interface{}) {
-	ErrorLogger.Printf(format, v...)
+	logf(slog.LevelError, format, v...)
 }
 
 // Debug logs a debug message
 func Debug(format string, v ...interface{}) {
-	DebugLogger.Printf(format, v...)
+	logf(slog.LevelDebug, format, v...)
 }
 
 // Fatal logs a fatal error and exits
 func Fatal(format string, v ...interface{}) {
-	ErrorLogger.Fatalf(format, v...)
+	logf(slog.LevelError, format, v...)
+	os.Exit(1)
 }
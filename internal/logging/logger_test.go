@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func resetLogging(t *testing.T) {
+	t.Cleanup(func() {
+		if err := Configure(Options{Level: "info", Format: FormatText}); err != nil {
+			t.Fatalf("Configure() error = %v", err)
+		}
+	})
+}
+
+func TestConfigureJSONFile(t *testing.T) {
+	resetLogging(t)
+	path := filepath.Join(t.TempDir(), "logs", "mission-control.log")
+	if err := Configure(Options{Level: "warn", Format: "json", File: path}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	Info("hidden %d", 1)
+	ctx := NewContext(context.Background(), "request_id", "7")
+	FromContext(ctx).Warn("slow call", "tool", "search")
+	Error("failed: %s", "boom")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log file has %d lines, want 2:\n%s", len(lines), data)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if record["msg"] != "slow call" || record["level"] != "WARN" || record["request_id"] != "7" || record["tool"] != "search" {
+		t.Errorf("record = %v", record)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil || record["msg"] != "failed: boom" {
+		t.Errorf("record = %v, err = %v", record, err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("log file mode = %v, err = %v", info.Mode().Perm(), err)
+	}
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	resetLogging(t)
+	if err := Configure(Options{Level: "verbose"}); err == nil {
+		t.Error("Configure() should reject level verbose")
+	}
+	if err := Configure(Options{Format: "xml"}); err == nil {
+		t.Error("Configure() should reject format xml")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("openRotatingFile() error = %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	for name, want := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		data, err := os.ReadFile(name)
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(name), data, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 should not exist, err = %v", path, err)
+	}
+}
@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/launch01/mission-control/internal/logging"
+)
+
+// MaxBatchSize is the maximum number of requests sent in a single JSON-RPC batch
+const MaxBatchSize = 50
+
+// Batch support states cached on the client after the first batch attempt
+const (
+	batchUnknown int32 = iota
+	batchSupported
+	batchUnsupported
+)
+
+// BatchCall describes a single request within a JSON-RPC batch
+type BatchCall struct {
+	Method string
+	Params interface{}
+}
+
+// BatchResult holds the outcome of a single call within a batch
+type BatchResult struct {
+	Result json.RawMessage
+	Err    error
+}
+
+// ToolCall describes a single tools/call invocation
+type ToolCall struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// errBatchRejected signals that the server does not accept batch arrays
+var errBatchRejected = errors.New("server rejected JSON-RPC batch")
+
+// CallBatch sends the calls as JSON-RPC 2.0 batch arrays and returns one result per call,
+// in the same order. Servers that reject batches are remembered and served sequentially.
+// The returned error is only set when the batch as a whole could not be delivered.
+func (c *Client) CallBatch(ctx context.Context, calls []BatchCall) ([]BatchResult, error) {
+	results := make([]BatchResult, len(calls))
+
+	for start := 0; start < len(calls); start += MaxBatchSize {
+		end := start + MaxBatchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+
+		if c.batchSupport.Load() != batchUnsupported {
+			err := c.sendBatch(ctx, calls[start:end], results[start:end])
+			if err == nil {
+				c.batchSupport.Store(batchSupported)
+				continue
+			}
+			if !errors.Is(err, errBatchRejected) {
+				return nil, err
+			}
+			logging.Debug("MCP server does not support batches, falling back to sequential calls")
+			c.batchSupport.Store(batchUnsupported)
+		}
+
+		for i := start; i < end; i++ {
+			result, err := c.Call(ctx, calls[i].Method, calls[i].Params)
+			results[i] = BatchResult{Result: result, Err: err}
+		}
+	}
+
+	return results, nil
+}
+
+// CallToolBatch calls several tools in as few round trips as the server allows
+func (c *Client) CallToolBatch(ctx context.Context, calls []ToolCall) ([]BatchResult, error) {
+	batch := make([]BatchCall, len(calls))
+	for i, call := range calls {
+		batch[i] = BatchCall{
+			Method: "tools/call",
+			Params: map[string]interface{}{
+				"name":      call.Name,
+				"arguments": call.Arguments,
+			},
+		}
+	}
+
+	results, err := c.CallBatch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		if err := checkToolResult(calls[i].Name, results[i].Result); err != nil {
+			results[i] = BatchResult{Err: err}
+		}
+	}
+
+	return results, nil
+}
+
+// sendBatch sends one batch array and fills results by correlating response IDs
+func (c *Client) sendBatch(ctx context.Context, calls []BatchCall, results []BatchResult) error {
+	requests := make([]JSONRPCRequest, len(calls))
+	index := make(map[string]int, len(calls))
+	for i, call := range calls {
+		id := uuid.New().String()
+		requests[i] = JSONRPCRequest{
+			JSONRPC: "2.0",
+			ID:      id,
+			Method:  call.Method,
+			Params:  call.Params,
+		}
+		index[id] = i
+	}
+
+	reqBody, err := json.Marshal(requests)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	logging.Debug("MCP Batch Request: %d calls %s", len(calls), string(reqBody))
+
+	body, err := c.post(ctx, reqBody)
+	if err != nil {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && isBatchRejectionStatus(httpErr.StatusCode) {
+			return errBatchRejected
+		}
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			return batchError(rpcErr, results)
+		}
+		return err
+	}
+
+	var responses []JSONRPCResponse
+	if err := json.Unmarshal(body, &responses); err != nil {
+		// A single error object in reply to an array applies to the whole batch
+		var single JSONRPCResponse
+		if json.Unmarshal(body, &single) == nil && single.Error != nil {
+			return batchError(newRPCError(single.Error, http.StatusOK), results)
+		}
+		return fmt.Errorf("failed to unmarshal batch response: %w", err)
+	}
+
+	seen := make([]bool, len(calls))
+	for _, response := range responses {
+		i, ok := index[response.ID]
+		if !ok || seen[i] {
+			logging.Debug("Ignoring batch response with unexpected ID %q", response.ID)
+			continue
+		}
+		seen[i] = true
+
+		if response.Error != nil {
+			results[i] = BatchResult{Err: newRPCError(response.Error, http.StatusOK)}
+			continue
+		}
+		results[i] = BatchResult{Result: response.Result}
+	}
+
+	for i := range calls {
+		if !seen[i] {
+			results[i] = BatchResult{Err: fmt.Errorf("no response for %s in batch", calls[i].Method)}
+		}
+	}
+
+	return nil
+}
+
+// batchError handles an error returned for a whole batch. Invalid request and method
+// not found mean the server refuses batch arrays; any other error, such as an auth
+// or transient server error, is reported for every call without giving up on batches.
+func batchError(err *RPCError, results []BatchResult) error {
+	if err.Code == CodeInvalidRequest || err.Code == CodeMethodNotFound {
+		return errBatchRejected
+	}
+	for i := range results {
+		results[i] = BatchResult{Err: err}
+	}
+	return nil
+}
+
+// isBatchRejectionStatus reports whether an HTTP status indicates the server refuses batch arrays
+func isBatchRejectionStatus(code int) bool {
+	switch code {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
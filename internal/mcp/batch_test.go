@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCallBatchCorrelatesResponses(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var batch []JSONRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("Expected batch array: %v", err)
+			return
+		}
+
+		// Reply in reverse order, with an error for the second call
+		var responses []JSONRPCResponse
+		for i := len(batch) - 1; i >= 0; i-- {
+			response := JSONRPCResponse{JSONRPC: "2.0", ID: batch[i].ID}
+			if i == 1 {
+				response.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+			} else {
+				params := batch[i].Params.(map[string]interface{})
+				result, _ := json.Marshal(map[string]interface{}{"echo": params["name"]})
+				response.Result = result
+			}
+			responses = append(responses, response)
+		}
+		json.NewEncoder(w).Encode(responses)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "header")
+	results, err := client.CallToolBatch(context.Background(), []ToolCall{
+		{Name: "first"},
+		{Name: "second"},
+		{Name: "third"},
+	})
+	if err != nil {
+		t.Fatalf("CallToolBatch() error = %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected 1 HTTP request, got %d", requests)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	for i, name := range []string{"first", "", "third"} {
+		if name == "" {
+			if results[i].Err == nil {
+				t.Errorf("Expected error for call %d", i)
+			}
+			continue
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal(results[i].Result, &data); err != nil {
+			t.Fatalf("Failed to unmarshal result %d: %v", i, err)
+		}
+		if data["echo"] != name {
+			t.Errorf("Result %d echo = %v, want %s", i, data["echo"], name)
+		}
+	}
+}
+
+func TestCallBatchMissingResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []JSONRPCRequest
+		json.NewDecoder(r.Body).Decode(&batch)
+
+		json.NewEncoder(w).Encode([]JSONRPCResponse{
+			{JSONRPC: "2.0", ID: batch[0].ID, Result: json.RawMessage(`{}`)},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "header")
+	results, err := client.CallBatch(context.Background(), []BatchCall{
+		{Method: "a"},
+		{Method: "b"},
+	})
+	if err != nil {
+		t.Fatalf("CallBatch() error = %v", err)
+	}
+
+	if results[0].Err != nil {
+		t.Errorf("Unexpected error for first call: %v", results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("Expected error for call without a response")
+	}
+}
+
+func TestCallBatchFallsBackToSequential(t *testing.T) {
+	batchAttempts := 0
+	singleCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+			batchAttempts++
+			json.NewEncoder(w).Encode(JSONRPCResponse{
+				JSONRPC: "2.0",
+				Error:   &JSONRPCError{Code: -32600, Message: "Batch requests are not supported"},
+			})
+			return
+		}
+
+		singleCalls++
+		var request JSONRPCRequest
+		json.Unmarshal(body, &request)
+		json.NewEncoder(w).Encode(JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      request.ID,
+			Result:  json.RawMessage(`{"ok": true}`),
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "header")
+	calls := []BatchCall{{Method: "a"}, {Method: "b"}}
+
+	for round := 0; round < 2; round++ {
+		results, err := client.CallBatch(context.Background(), calls)
+		if err != nil {
+			t.Fatalf("CallBatch() error = %v", err)
+		}
+		for i, result := range results {
+			if result.Err != nil {
+				t.Errorf("Round %d call %d error = %v", round, i, result.Err)
+			}
+		}
+	}
+
+	if batchAttempts != 1 {
+		t.Errorf("Expected a single batch attempt, got %d", batchAttempts)
+	}
+	if singleCalls != 4 {
+		t.Errorf("Expected 4 sequential calls, got %d", singleCalls)
+	}
+}
+
+func TestCallBatchKeepsBatchingAfterBatchError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		code   int
+	}{
+		{"auth error", http.StatusUnauthorized, -32001},
+		{"transient server error", http.StatusOK, CodeInternalError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batchAttempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var batch []JSONRPCRequest
+				if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+					t.Errorf("Expected batch array: %v", err)
+					return
+				}
+				batchAttempts++
+
+				w.Header().Set("Content-Type", "application/json")
+				if batchAttempts == 1 {
+					w.WriteHeader(tt.status)
+					json.NewEncoder(w).Encode(JSONRPCResponse{
+						JSONRPC: "2.0",
+						Error:   &JSONRPCError{Code: tt.code, Message: "try again"},
+					})
+					return
+				}
+				responses := make([]JSONRPCResponse, len(batch))
+				for i, req := range batch {
+					responses[i] = JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`{}`)}
+				}
+				json.NewEncoder(w).Encode(responses)
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "header")
+			calls := []BatchCall{{Method: "a"}, {Method: "b"}}
+
+			results, err := client.CallBatch(context.Background(), calls)
+			if err != nil {
+				t.Fatalf("CallBatch() error = %v", err)
+			}
+			for i, result := range results {
+				var rpcErr *RPCError
+				if !errors.As(result.Err, &rpcErr) || rpcErr.Code != tt.code {
+					t.Errorf("call %d error = %v, want the batch's error", i, result.Err)
+				}
+			}
+
+			results, err = client.CallBatch(context.Background(), calls)
+			if err != nil {
+				t.Fatalf("CallBatch() error = %v", err)
+			}
+			for i, result := range results {
+				if result.Err != nil {
+					t.Errorf("call %d error = %v", i, result.Err)
+				}
+			}
+			if batchAttempts != 2 {
+				t.Errorf("Expected the second round to be batched too, got %d batch attempts", batchAttempts)
+			}
+		})
+	}
+}
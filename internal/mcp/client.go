@@ -1,12 +1,12 @@
 package mcp
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,10 +15,10 @@ import (
 
 // Client represents an MCP client
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
-	authMode   string
-	token      string
+	transport    Transport
+	mu           sync.RWMutex
+	token        string
+	batchSupport atomic.Int32
 }
 
 // JSONRPCRequest represents a JSON-RPC 2.0 request
@@ -39,9 +39,9 @@ type JSONRPCResponse struct {
 
 // JSONRPCError represents a JSON-RPC 2.0 error
 type JSONRPCError struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
 }
 
 // Tool represents an MCP tool
@@ -49,21 +49,63 @@ type Tool struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	InputSchema map[string]interface{} `json:"inputSchema"`
+
+	// Server names the MCP server the tool belongs to when tools are aggregated
+	Server string `json:"-"`
+}
+
+// Resource represents an MCP resource
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+
+	// Server names the MCP server the resource belongs to when resources are aggregated
+	Server string `json:"-"`
+}
+
+// ResourceTemplate represents a parameterized MCP resource
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
 }
 
-// NewClient creates a new MCP client
+// Prompt represents an MCP prompt
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument describes an argument accepted by a prompt
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// NewClient creates a new MCP client for a server reachable over HTTP
 func NewClient(baseURL, authMode string) *Client {
-	return &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		authMode: authMode,
-	}
+	return NewClientWithTransport(NewHTTPTransport(baseURL, authMode))
+}
+
+// NewClientWithTransport creates a new MCP client on top of the given transport
+func NewClientWithTransport(transport Transport) *Client {
+	return &Client{transport: transport}
+}
+
+// Close releases the transport, stopping any server process it manages
+func (c *Client) Close() error {
+	return c.transport.Close()
 }
 
 // SetToken sets the authentication token
 func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.token = token
 }
 
@@ -81,52 +123,78 @@ func (c *Client) Call(ctx context.Context, method string, params interface{}) (j
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	logging.Debug("MCP Request: %s %s", method, string(reqBody))
+	log := logging.FromContext(ctx).With("method", method, "rpc_id", request.ID)
+	log.Debug("MCP request", "body", string(reqBody))
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL, bytes.NewReader(reqBody))
+	start := time.Now()
+	body, err := c.post(ctx, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		log.Debug("MCP call failed", "duration", time.Since(start), "error", err)
+		return nil, err
 	}
+	log.Debug("MCP call", "duration", time.Since(start))
 
-	req.Header.Set("Content-Type", "application/json")
-
-	// Add authentication based on mode
-	if c.token != "" {
-		if c.authMode == "header" {
-			req.Header.Set("Authorization", "Bearer "+c.token)
-		} else if c.authMode == "context" {
-			// For context mode, we'd add token to params
-			// This is server-dependent
-		}
+	var response JSONRPCResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	if response.Error != nil {
+		return nil, newRPCError(response.Error, http.StatusOK)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	return response.Result, nil
+}
+
+// post sends an encoded JSON-RPC payload to the MCP server and returns the raw response body
+func (c *Client) post(ctx context.Context, payload []byte) ([]byte, error) {
+	c.mu.RLock()
+	token := c.token
+	c.mu.RUnlock()
+
+	body, err := c.transport.RoundTrip(ctx, payload, token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
-	logging.Debug("MCP Response: %s", string(body))
+	logging.FromContext(ctx).Debug("MCP response", "body", string(body))
+	return body, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("MCP server returned status %d: %s", resp.StatusCode, string(body))
-	}
+// InitializeResult is the server's reply to the initialize handshake
+type InitializeResult struct {
+	ProtocolVersion string `json:"protocolVersion"`
+	ServerInfo      struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"serverInfo"`
+}
 
-	var response JSONRPCResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+// initializeParams are the parameters of the initialize request
+func initializeParams() map[string]interface{} {
+	return map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": map[string]interface{}{
+			"name":    "mission-control",
+			"version": "1.0.0",
+		},
 	}
+}
 
-	if response.Error != nil {
-		return nil, fmt.Errorf("MCP error %d: %s", response.Error.Code, response.Error.Message)
+// Initialize performs the initialize handshake with a server reached over HTTP.
+// The stdio transport performs it itself when it starts the server.
+func (c *Client) Initialize(ctx context.Context) (*InitializeResult, error) {
+	result, err := c.Call(ctx, "initialize", initializeParams())
+	if err != nil {
+		return nil, err
 	}
 
-	return response.Result, nil
+	var initResult InitializeResult
+	if err := json.Unmarshal(result, &initResult); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal initialize result: %w", err)
+	}
+	return &initResult, nil
 }
 
 // ListTools lists all available tools
@@ -147,12 +215,90 @@ func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
 	return response.Tools, nil
 }
 
-// CallTool calls a specific tool
+// CallTool calls a specific tool. A result with isError set is returned as a *ToolError.
 func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (json.RawMessage, error) {
 	params := map[string]interface{}{
 		"name":      name,
 		"arguments": arguments,
 	}
 
-	return c.Call(ctx, "tools/call", params)
+	result, err := c.Call(ctx, "tools/call", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkToolResult(name, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ListResources lists the resources exposed by the server
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	result, err := c.Call(ctx, "resources/list", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	var response struct {
+		Resources []Resource `json:"resources"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse resources response: %w", err)
+	}
+
+	return response.Resources, nil
+}
+
+// ListResourceTemplates lists the resource templates exposed by the server
+func (c *Client) ListResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	result, err := c.Call(ctx, "resources/templates/list", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource templates: %w", err)
+	}
+
+	var response struct {
+		ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse resource templates response: %w", err)
+	}
+
+	return response.ResourceTemplates, nil
+}
+
+// ReadResource reads a resource by URI and returns the raw result
+func (c *Client) ReadResource(ctx context.Context, uri string) (json.RawMessage, error) {
+	return c.Call(ctx, "resources/read", map[string]interface{}{"uri": uri})
+}
+
+// ListPrompts lists the prompts exposed by the server
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	result, err := c.Call(ctx, "prompts/list", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	var response struct {
+		Prompts []Prompt `json:"prompts"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse prompts response: %w", err)
+	}
+
+	return response.Prompts, nil
+}
+
+// GetPrompt renders a prompt with the given arguments and returns the raw result
+func (c *Client) GetPrompt(ctx context.Context, name string, arguments map[string]string) (json.RawMessage, error) {
+	params := map[string]interface{}{
+		"name":      name,
+		"arguments": arguments,
+	}
+
+	return c.Call(ctx, "prompts/get", params)
 }
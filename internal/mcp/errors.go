@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors for classifying MCP failures with errors.Is
+var (
+	// ErrTransport means the request never produced an HTTP response
+	ErrTransport = errors.New("mcp: transport failure")
+	// ErrHTTPStatus means the server answered with a non-200 HTTP status
+	ErrHTTPStatus = errors.New("mcp: unexpected HTTP status")
+	// ErrUnauthorized means the server rejected the credentials (HTTP 401)
+	ErrUnauthorized = errors.New("mcp: unauthorized")
+	// ErrForbidden means the credentials lack permission (HTTP 403)
+	ErrForbidden = errors.New("mcp: forbidden")
+	// ErrRateLimited means the server or upstream API throttled the request (HTTP 429)
+	ErrRateLimited = errors.New("mcp: rate limited")
+	// ErrServer means the server failed internally (HTTP 5xx or JSON-RPC internal error)
+	ErrServer = errors.New("mcp: server error")
+	// ErrRPC matches every JSON-RPC error object returned by the server
+	ErrRPC = errors.New("mcp: JSON-RPC error")
+	// ErrParse matches JSON-RPC code -32700
+	ErrParse = errors.New("mcp: parse error")
+	// ErrInvalidRequest matches JSON-RPC code -32600
+	ErrInvalidRequest = errors.New("mcp: invalid request")
+	// ErrMethodNotFound matches JSON-RPC code -32601
+	ErrMethodNotFound = errors.New("mcp: method not found")
+	// ErrInvalidParams matches JSON-RPC code -32602
+	ErrInvalidParams = errors.New("mcp: invalid params")
+	// ErrToolFailed means a tool ran but reported isError in its result
+	ErrToolFailed = errors.New("mcp: tool returned an error")
+)
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// TransportError wraps a network or I/O failure talking to the MCP server
+type TransportError struct {
+	Op  string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("failed to %s: %v", e.Op, e.Err)
+}
+
+func (e *TransportError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrTransport
+func (e *TransportError) Is(target error) bool { return target == ErrTransport }
+
+// HTTPError reports a non-200 HTTP response from the MCP server
+type HTTPError struct {
+	StatusCode int
+	Body       string
+	RetryAfter string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("MCP server returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// Is matches ErrHTTPStatus and the sentinel for the status class
+func (e *HTTPError) Is(target error) bool {
+	if target == ErrHTTPStatus {
+		return true
+	}
+	return statusSentinel(e.StatusCode) == target && target != nil
+}
+
+// RPCError is a JSON-RPC error object returned by the server, with its data preserved
+type RPCError struct {
+	Code       int
+	Message    string
+	Data       json.RawMessage
+	HTTPStatus int
+}
+
+func (e *RPCError) Error() string {
+	if len(e.Data) > 0 && string(e.Data) != "null" {
+		return fmt.Sprintf("MCP error %d: %s (%s)", e.Code, e.Message, string(e.Data))
+	}
+	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
+}
+
+// Is matches ErrRPC, the sentinel for the JSON-RPC code and the HTTP status class
+func (e *RPCError) Is(target error) bool {
+	if target == nil {
+		return false
+	}
+	if target == ErrRPC {
+		return true
+	}
+	switch e.Code {
+	case CodeParseError:
+		if target == ErrParse {
+			return true
+		}
+	case CodeInvalidRequest:
+		if target == ErrInvalidRequest {
+			return true
+		}
+	case CodeMethodNotFound:
+		if target == ErrMethodNotFound {
+			return true
+		}
+	case CodeInvalidParams:
+		if target == ErrInvalidParams {
+			return true
+		}
+	case CodeInternalError:
+		if target == ErrServer {
+			return true
+		}
+	}
+	return statusSentinel(e.HTTPStatus) == target
+}
+
+// ToolError reports a tools/call result with isError set
+type ToolError struct {
+	Tool    string
+	Content json.RawMessage
+}
+
+func (e *ToolError) Error() string {
+	if text := e.Text(); text != "" {
+		return fmt.Sprintf("tool %s failed: %s", e.Tool, text)
+	}
+	return fmt.Sprintf("tool %s failed", e.Tool)
+}
+
+// Is reports whether target is ErrToolFailed
+func (e *ToolError) Is(target error) bool { return target == ErrToolFailed }
+
+// Text joins the text items of the tool's content
+func (e *ToolError) Text() string {
+	var items []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(e.Content, &items); err != nil {
+		return ""
+	}
+
+	var parts []string
+	for _, item := range items {
+		if item.Type == "text" && item.Text != "" {
+			parts = append(parts, item.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// newRPCError converts a wire error object into an RPCError
+func newRPCError(e *JSONRPCError, httpStatus int) *RPCError {
+	return &RPCError{
+		Code:       e.Code,
+		Message:    e.Message,
+		Data:       e.Data,
+		HTTPStatus: httpStatus,
+	}
+}
+
+// checkToolResult returns a ToolError when a tools/call result has isError set
+func checkToolResult(name string, result json.RawMessage) error {
+	var parsed struct {
+		IsError bool            `json:"isError"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(result, &parsed); err != nil || !parsed.IsError {
+		return nil
+	}
+	return &ToolError{Tool: name, Content: parsed.Content}
+}
+
+// statusSentinel maps an HTTP status code to its sentinel error
+func statusSentinel(code int) error {
+	switch {
+	case code == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case code == http.StatusForbidden:
+		return ErrForbidden
+	case code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case code >= 500:
+		return ErrServer
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPErrorClassification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "header")
+	_, err := client.Call(context.Background(), "test", nil)
+
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+	if !errors.Is(err, ErrHTTPStatus) {
+		t.Errorf("Expected ErrHTTPStatus, got %v", err)
+	}
+	if errors.Is(err, ErrUnauthorized) {
+		t.Error("429 should not match ErrUnauthorized")
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected *HTTPError, got %T", err)
+	}
+	if httpErr.StatusCode != http.StatusTooManyRequests || httpErr.RetryAfter != "30" {
+		t.Errorf("HTTPError = %+v", httpErr)
+	}
+}
+
+func TestRPCErrorPreservesData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      "test-id",
+			Error: &JSONRPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    json.RawMessage(`{"field":"limit"}`),
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "header")
+	_, err := client.Call(context.Background(), "test", nil)
+
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("Expected *RPCError, got %T", err)
+	}
+	if string(rpcErr.Data) != `{"field":"limit"}` {
+		t.Errorf("Data = %s", rpcErr.Data)
+	}
+	if rpcErr.HTTPStatus != http.StatusUnauthorized {
+		t.Errorf("HTTPStatus = %d, want 401", rpcErr.HTTPStatus)
+	}
+	for _, target := range []error{ErrRPC, ErrInvalidParams, ErrUnauthorized} {
+		if !errors.Is(err, target) {
+			t.Errorf("Expected errors.Is(err, %v)", target)
+		}
+	}
+}
+
+func TestToolErrorFromIsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      "test-id",
+			Result:  json.RawMessage(`{"isError": true, "content": [{"type": "text", "text": "contact not found"}]}`),
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "header")
+	_, err := client.CallTool(context.Background(), "get_contact", nil)
+
+	if !errors.Is(err, ErrToolFailed) {
+		t.Fatalf("Expected ErrToolFailed, got %v", err)
+	}
+
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) {
+		t.Fatalf("Expected *ToolError, got %T", err)
+	}
+	if toolErr.Tool != "get_contact" || toolErr.Text() != "contact not found" {
+		t.Errorf("ToolError = %v", toolErr)
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client := NewClient(url, "header")
+	_, err := client.Call(context.Background(), "test", nil)
+
+	if !errors.Is(err, ErrTransport) {
+		t.Errorf("Expected ErrTransport, got %v", err)
+	}
+}
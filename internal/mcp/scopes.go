@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// missingScopesCategory is the error category HubSpot uses when a token lacks a scope
+const missingScopesCategory = "MISSING_SCOPES"
+
+var (
+	requiredScopesPattern = regexp.MustCompile(`"required(?:Granular)?Scopes"\s*:\s*\[([^\]]*)\]`)
+	quotedPattern         = regexp.MustCompile(`"([^"]+)"`)
+)
+
+// MissingScopes reports whether err is HubSpot rejecting the token for lacking a scope,
+// and returns the scopes it named. The HubSpot error body may arrive as an HTTP error
+// body, in a JSON-RPC error's message or data, or as the text of a failed tool result,
+// possibly JSON-encoded a second time.
+func MissingScopes(err error) ([]string, bool) {
+	var texts []string
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		texts = append(texts, httpErr.Body)
+	}
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		texts = append(texts, rpcErr.Message, string(rpcErr.Data))
+	}
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		texts = append(texts, toolErr.Text())
+	}
+
+	found := false
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, text := range texts {
+		text = strings.ReplaceAll(text, `\"`, `"`)
+
+		matches := requiredScopesPattern.FindAllStringSubmatch(text, -1)
+		if len(matches) == 0 && !strings.Contains(text, missingScopesCategory) {
+			continue
+		}
+		found = true
+
+		for _, match := range matches {
+			for _, quoted := range quotedPattern.FindAllStringSubmatch(match[1], -1) {
+				if scope := quoted[1]; !seen[scope] {
+					seen[scope] = true
+					scopes = append(scopes, scope)
+				}
+			}
+		}
+	}
+
+	return scopes, found
+}
@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+const missingScopesBody = `{"status":"error","message":"This app hasn't been granted all required scopes to make this call.","category":"MISSING_SCOPES","errors":[{"message":"One or more of the following scopes are required.","context":{"requiredGranularScopes":["crm.objects.tickets.read","crm.objects.custom.read"]}}]}`
+
+func TestMissingScopes(t *testing.T) {
+	toolContent, _ := json.Marshal([]map[string]string{{"type": "text", "text": missingScopesBody}})
+	doubleEncoded, _ := json.Marshal(missingScopesBody)
+
+	tests := []struct {
+		name      string
+		err       error
+		wantFound bool
+		want      []string
+	}{
+		{"HTTP body", &HTTPError{StatusCode: 403, Body: missingScopesBody}, true, []string{"crm.objects.tickets.read", "crm.objects.custom.read"}},
+		{"RPC data", &RPCError{Code: -32603, Message: "HubSpot API error", Data: doubleEncoded}, true, []string{"crm.objects.tickets.read", "crm.objects.custom.read"}},
+		{"tool result", fmt.Errorf("call failed: %w", &ToolError{Tool: "get_tickets", Content: toolContent}), true, []string{"crm.objects.tickets.read", "crm.objects.custom.read"}},
+		{"category only", &HTTPError{StatusCode: 403, Body: `{"category":"MISSING_SCOPES"}`}, true, nil},
+		{"other forbidden", &HTTPError{StatusCode: 403, Body: `{"category":"FORBIDDEN"}`}, false, nil},
+		{"unrelated", fmt.Errorf("boom"), false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := MissingScopes(tt.err)
+			if found != tt.wantFound {
+				t.Fatalf("MissingScopes() found = %v, want %v", found, tt.wantFound)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MissingScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/launch01/mission-control/internal/logging"
+)
+
+// ProtocolVersion is the MCP protocol revision announced during initialize
+const ProtocolVersion = "2024-11-05"
+
+// StdioOptions configures a server process spoken to over stdin/stdout
+type StdioOptions struct {
+	Command string
+	Args    []string
+	Env     []string
+	// TokenEnv names the environment variable that carries the access token to the process
+	TokenEnv string
+	// Timeout bounds each exchange with the process (default 30 seconds)
+	Timeout time.Duration
+}
+
+const defaultStdioTimeout = 30 * time.Second
+
+// StdioTransport runs an MCP server as a child process and exchanges
+// newline-delimited JSON-RPC messages with it. The process is started lazily,
+// and restarted whenever the token changes so it always runs with fresh credentials.
+type StdioTransport struct {
+	opts StdioOptions
+
+	mu    sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan []byte
+	token string
+}
+
+// NewStdioTransport creates a transport for the given server command
+func NewStdioTransport(opts StdioOptions) *StdioTransport {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultStdioTimeout
+	}
+	return &StdioTransport{opts: opts}
+}
+
+// RoundTrip writes the payload to the process and waits for the matching reply
+func (t *StdioTransport) RoundTrip(ctx context.Context, payload []byte, token string) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cmd == nil || token != t.token {
+		if err := t.restart(ctx, token); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.exchange(ctx, payload)
+}
+
+// Close stops the server process
+func (t *StdioTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stop()
+	return nil
+}
+
+// restart stops any running process, starts a new one and performs the initialize handshake
+func (t *StdioTransport) restart(ctx context.Context, token string) error {
+	t.stop()
+
+	cmd := exec.Command(t.opts.Command, t.opts.Args...)
+	cmd.Env = append(os.Environ(), t.opts.Env...)
+	if t.opts.TokenEnv != "" && token != "" {
+		cmd.Env = append(cmd.Env, t.opts.TokenEnv+"="+token)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return &TransportError{Op: "open server stdin", Err: err}
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return &TransportError{Op: "open server stdout", Err: err}
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return &TransportError{Op: "open server stderr", Err: err}
+	}
+
+	if err := cmd.Start(); err != nil {
+		return &TransportError{Op: "start " + t.opts.Command, Err: err}
+	}
+
+	lines := make(chan []byte, 16)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			lines <- append([]byte(nil), line...)
+		}
+	}()
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logging.Debug("MCP server %s: %s", t.opts.Command, scanner.Text())
+		}
+	}()
+
+	t.cmd = cmd
+	t.stdin = stdin
+	t.lines = lines
+	t.token = token
+
+	if err := t.initialize(ctx); err != nil {
+		t.stop()
+		return err
+	}
+
+	return nil
+}
+
+// initialize performs the MCP initialize request and initialized notification
+func (t *StdioTransport) initialize(ctx context.Context) error {
+	request, err := json.Marshal(JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      uuid.New().String(),
+		Method:  "initialize",
+		Params:  initializeParams(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal initialize request: %w", err)
+	}
+
+	body, err := t.exchange(ctx, request)
+	if err != nil {
+		return fmt.Errorf("initialize handshake failed: %w", err)
+	}
+
+	var response JSONRPCResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal initialize response: %w", err)
+	}
+	if response.Error != nil {
+		return newRPCError(response.Error, 0)
+	}
+
+	notification := []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n")
+	if _, err := t.stdin.Write(notification); err != nil {
+		return &TransportError{Op: "write to server", Err: err}
+	}
+
+	return nil
+}
+
+// exchange writes one payload and reads lines until the reply to it arrives, or the
+// timeout passes. Notifications and other unrelated messages from the server are skipped.
+func (t *StdioTransport) exchange(ctx context.Context, payload []byte) ([]byte, error) {
+	batch := len(payload) > 0 && bytes.TrimSpace(payload)[0] == '['
+
+	var expectedID string
+	if !batch {
+		var request struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return nil, fmt.Errorf("failed to parse request: %w", err)
+		}
+		expectedID = request.ID
+	}
+
+	if _, err := t.stdin.Write(append(append([]byte(nil), payload...), '\n')); err != nil {
+		t.stop()
+		return nil, &TransportError{Op: "write to server", Err: err}
+	}
+
+	timer := time.NewTimer(t.opts.Timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// The reply may still arrive later and would be mistaken for the next one
+			t.stop()
+			return nil, &TransportError{Op: "wait for server", Err: ctx.Err()}
+		case <-timer.C:
+			t.stop()
+			return nil, &TransportError{Op: "wait for server", Err: fmt.Errorf("no reply within %s", t.opts.Timeout)}
+		case line, ok := <-t.lines:
+			if !ok {
+				t.stop()
+				return nil, &TransportError{Op: "read from server", Err: errors.New("server process exited")}
+			}
+
+			if batch && line[0] == '[' {
+				return line, nil
+			}
+
+			var message struct {
+				ID     *string         `json:"id"`
+				Method string          `json:"method"`
+				Result json.RawMessage `json:"result"`
+				Error  json.RawMessage `json:"error"`
+			}
+			if err := json.Unmarshal(line, &message); err != nil {
+				logging.Debug("Ignoring non-JSON output from MCP server: %s", string(line))
+				continue
+			}
+			if message.Method != "" {
+				continue
+			}
+			if batch {
+				// An error without an ID is the reply of a server that can't parse arrays
+				if message.ID == nil && message.Error != nil {
+					return nil, errBatchRejected
+				}
+				continue
+			}
+			if message.ID != nil && *message.ID == expectedID {
+				return line, nil
+			}
+		}
+	}
+}
+
+// stop terminates the server process if one is running
+func (t *StdioTransport) stop() {
+	if t.cmd == nil {
+		return
+	}
+
+	t.stdin.Close()
+	if t.cmd.Process != nil {
+		t.cmd.Process.Kill()
+	}
+	t.cmd.Wait()
+
+	t.cmd = nil
+	t.stdin = nil
+	t.lines = nil
+}
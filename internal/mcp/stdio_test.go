@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStdioHelperProcess acts as a minimal stdio MCP server when run as a child process
+func TestStdioHelperProcess(t *testing.T) {
+	if os.Getenv("MC_STDIO_HELPER") != "1" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "[") {
+			// Like many line-based servers, refuse batch arrays with an error without an ID
+			fmt.Println(`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`)
+			continue
+		}
+		var request JSONRPCRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil || request.ID == "" || request.Method == "hang" {
+			continue
+		}
+
+		// Emit a notification first to check that the client skips it
+		fmt.Println(`{"jsonrpc":"2.0","method":"notifications/message","params":{}}`)
+
+		result, _ := json.Marshal(map[string]interface{}{
+			"method": request.Method,
+			"token":  os.Getenv("TEST_TOKEN"),
+		})
+		response, _ := json.Marshal(JSONRPCResponse{JSONRPC: "2.0", ID: request.ID, Result: result})
+		fmt.Println(string(response))
+	}
+	os.Exit(0)
+}
+
+func TestStdioTransport(t *testing.T) {
+	transport := NewStdioTransport(StdioOptions{
+		Command:  os.Args[0],
+		Args:     []string{"-test.run=TestStdioHelperProcess"},
+		Env:      []string{"MC_STDIO_HELPER=1"},
+		TokenEnv: "TEST_TOKEN",
+	})
+	client := NewClientWithTransport(transport)
+	defer client.Close()
+
+	for _, token := range []string{"first-token", "second-token"} {
+		client.SetToken(token)
+
+		result, err := client.Call(context.Background(), "tools/list", nil)
+		if err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+
+		var data map[string]string
+		if err := json.Unmarshal(result, &data); err != nil {
+			t.Fatalf("Failed to unmarshal result: %v", err)
+		}
+
+		if data["method"] != "tools/list" {
+			t.Errorf("method = %s, want tools/list", data["method"])
+		}
+		// A token change restarts the process with the new token
+		if data["token"] != token {
+			t.Errorf("token = %s, want %s", data["token"], token)
+		}
+	}
+}
+
+func TestStdioBatchFallback(t *testing.T) {
+	client := NewClientWithTransport(NewStdioTransport(StdioOptions{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestStdioHelperProcess"},
+		Env:     []string{"MC_STDIO_HELPER=1"},
+	}))
+	defer client.Close()
+
+	results, err := client.CallBatch(context.Background(), []BatchCall{{Method: "tools/list"}, {Method: "prompts/list"}})
+	if err != nil {
+		t.Fatalf("CallBatch() error = %v", err)
+	}
+	for i, method := range []string{"tools/list", "prompts/list"} {
+		var data map[string]string
+		if results[i].Err != nil || json.Unmarshal(results[i].Result, &data) != nil || data["method"] != method {
+			t.Errorf("result %d = %s, %v; want the sequential reply to %s", i, results[i].Result, results[i].Err, method)
+		}
+	}
+}
+
+func TestStdioTimeout(t *testing.T) {
+	client := NewClientWithTransport(NewStdioTransport(StdioOptions{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestStdioHelperProcess"},
+		Env:     []string{"MC_STDIO_HELPER=1"},
+		Timeout: 200 * time.Millisecond,
+	}))
+	defer client.Close()
+
+	var transportErr *TransportError
+	if _, err := client.Call(context.Background(), "hang", nil); !errors.As(err, &transportErr) {
+		t.Fatalf("Call() error = %v, want a transport error", err)
+	}
+	if _, err := client.Call(context.Background(), "tools/list", nil); err != nil {
+		t.Errorf("Call() after a timeout error = %v", err)
+	}
+}
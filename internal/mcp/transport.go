@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Transport delivers encoded JSON-RPC payloads to an MCP server
+type Transport interface {
+	// RoundTrip sends a request payload, authenticated with token when set, and returns the reply
+	RoundTrip(ctx context.Context, payload []byte, token string) ([]byte, error)
+	// Close releases any resources held by the transport
+	Close() error
+}
+
+// HTTPTransport posts JSON-RPC payloads to an MCP server over HTTP
+type HTTPTransport struct {
+	baseURL    string
+	authMode   string
+	httpClient *http.Client
+}
+
+// NewHTTPTransport creates a transport for the MCP server at baseURL
+func NewHTTPTransport(baseURL, authMode string) *HTTPTransport {
+	return &HTTPTransport{
+		baseURL:  baseURL,
+		authMode: authMode,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// RoundTrip posts the payload and returns the response body
+func (t *HTTPTransport) RoundTrip(ctx context.Context, payload []byte, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", t.baseURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, &TransportError{Op: "create request", Err: err}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	// Add authentication based on mode
+	if token != "" {
+		if t.authMode == "header" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if t.authMode == "context" {
+			// For context mode, we'd add token to params
+			// This is server-dependent
+		}
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, &TransportError{Op: "make request", Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &TransportError{Op: "read response", Err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// Prefer the JSON-RPC error object when the server sent one with the status
+		var response JSONRPCResponse
+		if json.Unmarshal(body, &response) == nil && response.Error != nil {
+			return nil, newRPCError(response.Error, resp.StatusCode)
+		}
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: resp.Header.Get("Retry-After"),
+		}
+	}
+
+	return body, nil
+}
+
+// Close is a no-op for HTTP transports
+func (t *HTTPTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,174 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/launch01/mission-control/internal/logging"
+)
+
+// CallbackResult is the outcome of the authorization redirect
+type CallbackResult struct {
+	Code string
+	Err  error
+}
+
+// CallbackServer receives the OAuth redirect on a loopback address.
+// It delivers exactly one result; later hits get a page saying the login is already complete.
+type CallbackServer struct {
+	path          string
+	expectedState string
+	listener      net.Listener
+	server        *http.Server
+	results       chan CallbackResult
+	delivered     bool
+	mu            sync.Mutex
+}
+
+var callbackPage = template.Must(template.New("callback").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 4em auto;">
+	<h1>{{.Title}}</h1>
+	<p>{{.Message}}</p>
+</body>
+</html>
+`))
+
+// NewCallbackServer binds addr, which must be a loopback address such as 127.0.0.1:8400
+// or [::1]:0, and starts serving the callback path. Bind failures are returned immediately.
+func NewCallbackServer(addr, path, expectedState string) (*CallbackServer, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callback address %q: %w", addr, err)
+	}
+	host, err = loopbackHost(host)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", net.JoinHostPort(host, port), err)
+	}
+
+	s := &CallbackServer{
+		path:          path,
+		expectedState: expectedState,
+		listener:      listener,
+		results:       make(chan CallbackResult, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, s.handle)
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logging.Error("Callback server error: %v", err)
+		}
+	}()
+
+	return s, nil
+}
+
+// Port returns the bound port, which differs from the requested one when port 0 was used
+func (s *CallbackServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Results delivers the single callback result
+func (s *CallbackServer) Results() <-chan CallbackResult {
+	return s.results
+}
+
+// Close shuts the server down
+func (s *CallbackServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+func (s *CallbackServer) handle(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Only a request carrying our state may conclude the login; anything else
+	// (a stale tab, a forged link) is turned away without aborting the flow
+	if query.Get("state") != s.expectedState {
+		logging.Error("Ignoring OAuth callback with invalid state")
+		renderCallbackPage(w, http.StatusBadRequest, "Authorization Failed", "Invalid state parameter. Start the login again from the terminal.")
+		return
+	}
+
+	var result CallbackResult
+	switch {
+	case query.Get("error") != "":
+		result.Err = fmt.Errorf("authorization failed: %s %s", query.Get("error"), query.Get("error_description"))
+	case query.Get("code") == "":
+		result.Err = fmt.Errorf("no authorization code received")
+	default:
+		result.Code = query.Get("code")
+	}
+
+	if !s.deliver(result) {
+		renderCallbackPage(w, http.StatusOK, "Already Completed", "This login has already been completed. You can close this window.")
+		return
+	}
+
+	if result.Err != nil {
+		renderCallbackPage(w, http.StatusBadRequest, "Authorization Failed", result.Err.Error())
+		return
+	}
+	renderCallbackPage(w, http.StatusOK, "Authorization Successful!", "You can close this window and return to the terminal.")
+}
+
+// deliver sends the first result and reports whether this call delivered it
+func (s *CallbackServer) deliver(result CallbackResult) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.delivered {
+		return false
+	}
+	s.delivered = true
+	s.results <- result
+	return true
+}
+
+func renderCallbackPage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	callbackPage.Execute(w, struct{ Title, Message string }{title, message})
+}
+
+// loopbackHost resolves the host the callback server may bind to; only loopback is allowed
+func loopbackHost(host string) (string, error) {
+	switch host {
+	case "", "localhost":
+		return "127.0.0.1", nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return host, nil
+	}
+	return "", fmt.Errorf("callback server must bind to a loopback address (127.0.0.1 or ::1), got %q", host)
+}
+
+// withPort returns the redirect URI with its port replaced
+func withPort(redirectURI string, port int) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", fmt.Errorf("invalid redirect URI: %w", err)
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+	return u.String(), nil
+}
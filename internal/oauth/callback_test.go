@@ -0,0 +1,107 @@
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallbackServerDeliversOnce(t *testing.T) {
+	server, err := NewCallbackServer("127.0.0.1:0", "/oauth/callback", "good-state")
+	if err != nil {
+		t.Fatalf("NewCallbackServer() error = %v", err)
+	}
+	defer server.Close()
+
+	base := fmt.Sprintf("http://127.0.0.1:%d/oauth/callback", server.Port())
+
+	// A wrong state is rejected without concluding the login
+	resp, err := http.Get(base + "?code=evil&state=bad-state")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid state status = %d, want 400", resp.StatusCode)
+	}
+
+	resp, err = http.Get(base + "?code=abc&state=good-state")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Callback status = %d, want 200", resp.StatusCode)
+	}
+
+	// A second hit must not panic or deliver another result
+	resp, err = http.Get(base + "?code=again&state=good-state")
+	if err != nil {
+		t.Fatalf("Second GET error = %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case result := <-server.Results():
+		if result.Err != nil || result.Code != "abc" {
+			t.Errorf("Result = %+v, want code abc", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No callback result delivered")
+	}
+
+	select {
+	case result := <-server.Results():
+		t.Errorf("Unexpected second result %+v", result)
+	default:
+	}
+}
+
+func TestCallbackServerErrorResult(t *testing.T) {
+	server, err := NewCallbackServer("127.0.0.1:0", "/cb", "s")
+	if err != nil {
+		t.Fatalf("NewCallbackServer() error = %v", err)
+	}
+	defer server.Close()
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/cb?error=access_denied&state=s", server.Port()))
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+
+	result := <-server.Results()
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "access_denied") {
+		t.Errorf("Result error = %v, want access_denied", result.Err)
+	}
+}
+
+func TestCallbackServerLoopbackOnly(t *testing.T) {
+	if _, err := NewCallbackServer("0.0.0.0:0", "/cb", "s"); err == nil {
+		t.Error("Expected error binding to a non-loopback address")
+	}
+}
+
+func TestCallbackServerBindFailure(t *testing.T) {
+	first, err := NewCallbackServer("127.0.0.1:0", "/cb", "s")
+	if err != nil {
+		t.Fatalf("NewCallbackServer() error = %v", err)
+	}
+	defer first.Close()
+
+	if _, err := NewCallbackServer(fmt.Sprintf("127.0.0.1:%d", first.Port()), "/cb", "s"); err == nil {
+		t.Error("Expected synchronous error when the port is taken")
+	}
+}
+
+func TestWithPort(t *testing.T) {
+	got, err := withPort("http://127.0.0.1:0/oauth/callback", 54321)
+	if err != nil {
+		t.Fatalf("withPort() error = %v", err)
+	}
+	if got != "http://127.0.0.1:54321/oauth/callback" {
+		t.Errorf("withPort() = %s", got)
+	}
+}
@@ -0,0 +1,327 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProtectedResourceMetadata is an MCP server's RFC 9728 protected resource metadata
+type ProtectedResourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+	ScopesSupported      []string `json:"scopes_supported"`
+}
+
+// AuthServerMetadata is an authorization server's RFC 8414 metadata
+type AuthServerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Discovery is what an MCP server advertises about how to obtain a token for it
+type Discovery struct {
+	// Resource is the RFC 8707 resource indicator for the server
+	Resource string
+	// Scopes are the scopes to request, from the server's challenge or metadata
+	Scopes     []string
+	AuthServer *AuthServerMetadata
+}
+
+// Provider returns an OAuth provider for the discovered authorization server
+func (d *Discovery) Provider() *Provider {
+	return &Provider{
+		Name:             d.AuthServer.Issuer,
+		AuthURL:          d.AuthServer.AuthorizationEndpoint,
+		TokenURL:         d.AuthServer.TokenEndpoint,
+		RevokeURL:        d.AuthServer.RevocationEndpoint,
+		RevokeStyle:      RevokeRFC7009,
+		ClientAuth:       ClientAuthBody,
+		DefaultExpiresIn: time.Hour,
+		Resource:         d.Resource,
+	}
+}
+
+// Discover finds the authorization server protecting an MCP server, following the MCP
+// authorization spec: the WWW-Authenticate challenge of an unauthenticated request or the
+// RFC 9728 well-known document names the authorization server, whose RFC 8414 metadata
+// gives the endpoints. Servers without protected resource metadata are treated as their
+// own authorization server.
+func Discover(ctx context.Context, serverURL string) (*Discovery, error) {
+	server, err := url.Parse(serverURL)
+	if err != nil || server.Scheme == "" || server.Host == "" {
+		return nil, fmt.Errorf("invalid MCP server URL %q", serverURL)
+	}
+
+	d := &Discovery{Resource: canonicalResource(server)}
+
+	challenge := probe(ctx, serverURL)
+
+	var candidates []string
+	if u := challenge["resource_metadata"]; u != "" {
+		candidates = append(candidates, u)
+	}
+	candidates = append(candidates, wellKnownURLs(server, "oauth-protected-resource")...)
+
+	var prm *ProtectedResourceMetadata
+	for _, u := range candidates {
+		var m ProtectedResourceMetadata
+		if err := getJSON(ctx, u, &m); err == nil && len(m.AuthorizationServers) > 0 {
+			prm = &m
+			break
+		}
+	}
+
+	issuer := server.Scheme + "://" + server.Host
+	if prm != nil {
+		issuer = prm.AuthorizationServers[0]
+		if prm.Resource != "" {
+			d.Resource = prm.Resource
+		}
+		d.Scopes = prm.ScopesSupported
+	}
+	if scope := challenge["scope"]; scope != "" {
+		d.Scopes = strings.Fields(scope)
+	}
+
+	d.AuthServer, err = fetchAuthServerMetadata(ctx, issuer, prm == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if methods := d.AuthServer.CodeChallengeMethodsSupported; len(methods) > 0 && !contains(methods, "S256") {
+		return nil, fmt.Errorf("authorization server %s does not support PKCE with S256", issuer)
+	}
+	if d.AuthServer.AuthorizationEndpoint == "" || d.AuthServer.TokenEndpoint == "" {
+		return nil, fmt.Errorf("authorization server %s does not advertise authorization and token endpoints", issuer)
+	}
+
+	return d, nil
+}
+
+// probe sends an unauthenticated initialize request and returns the parameters of
+// the Bearer challenge, if the server answers 401 with one
+func probe(ctx context.Context, serverURL string) map[string]string {
+	body := []byte(`{"jsonrpc":"2.0","id":"discovery","method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"mission-control","version":"1.0"}}}`)
+	req, err := http.NewRequestWithContext(ctx, "POST", serverURL, bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		if scheme, params, ok := strings.Cut(strings.TrimSpace(header), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return parseAuthParams(params)
+		}
+	}
+	return nil
+}
+
+// fetchAuthServerMetadata reads the RFC 8414 (or OpenID Connect) metadata of issuer.
+// For servers predating protected resource metadata, missing metadata falls back to
+// the default endpoint paths on the issuer.
+func fetchAuthServerMetadata(ctx context.Context, issuer string, legacy bool) (*AuthServerMetadata, error) {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid authorization server %q", issuer)
+	}
+
+	candidates := wellKnownURLs(u, "oauth-authorization-server")
+	candidates = append(candidates, wellKnownURLs(u, "openid-configuration")...)
+	candidates = append(candidates, strings.TrimRight(issuer, "/")+"/.well-known/openid-configuration")
+
+	for _, candidate := range candidates {
+		var m AuthServerMetadata
+		if err := getJSON(ctx, candidate, &m); err == nil && m.AuthorizationEndpoint != "" {
+			return &m, nil
+		}
+	}
+
+	if legacy {
+		base := u.Scheme + "://" + u.Host
+		return &AuthServerMetadata{
+			Issuer:                base,
+			AuthorizationEndpoint: base + "/authorize",
+			TokenEndpoint:         base + "/token",
+			RegistrationEndpoint:  base + "/register",
+		}, nil
+	}
+	return nil, fmt.Errorf("no authorization server metadata found for %s", issuer)
+}
+
+// wellKnownURLs returns the well-known locations for u: the suffix inserted between the
+// host and u's path, then at the root
+func wellKnownURLs(u *url.URL, suffix string) []string {
+	base := u.Scheme + "://" + u.Host + "/.well-known/" + suffix
+	path := strings.TrimRight(u.Path, "/")
+	if path == "" {
+		return []string{base}
+	}
+	return []string{base + path, base}
+}
+
+// canonicalResource returns the RFC 8707 resource indicator for an MCP server URL
+func canonicalResource(u *url.URL) string {
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + strings.TrimRight(u.EscapedPath(), "/")
+}
+
+// parseAuthParams parses the comma-separated auth-params of a WWW-Authenticate challenge
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimSpace(s[eq+1:])
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			if i < len(s) {
+				i++ // closing quote
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+
+		s = strings.TrimLeft(s, ", ")
+	}
+	return params
+}
+
+// ClientRegistration is the client issued by RFC 7591 dynamic client registration
+type ClientRegistration struct {
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret"`
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method"`
+}
+
+// ClientAuth returns the provider client authentication for the registered client
+func (r *ClientRegistration) ClientAuth() string {
+	if r.TokenEndpointAuthMethod == "client_secret_basic" {
+		return ClientAuthBasic
+	}
+	return ClientAuthBody
+}
+
+// Register registers mission-control as a public client with the authorization server
+func (d *Discovery) Register(ctx context.Context, redirectURI string) (*ClientRegistration, error) {
+	if d.AuthServer.RegistrationEndpoint == "" {
+		return nil, fmt.Errorf("authorization server %s does not support dynamic client registration - configure a client ID", d.AuthServer.Issuer)
+	}
+
+	request := map[string]interface{}{
+		"client_name":                "mission-control",
+		"redirect_uris":              []string{redirectURI},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+	}
+	if len(d.Scopes) > 0 {
+		request["scope"] = strings.Join(d.Scopes, " ")
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal registration: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.AuthServer.RegistrationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register client: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("client registration failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var reg ClientRegistration
+	if err := json.NewDecoder(resp.Body).Decode(&reg); err != nil {
+		return nil, fmt.Errorf("failed to decode registration response: %w", err)
+	}
+	if reg.ClientID == "" {
+		return nil, fmt.Errorf("registration response has no client_id")
+	}
+
+	return &reg, nil
+}
+
+// getJSON fetches u and decodes a 200 JSON response into v
+func getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverAndRegister(t *testing.T) {
+	var serverURL string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", resource_metadata="`+serverURL+`/meta/prm", scope="mcp:tools mcp:read"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/meta/prm", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"resource":              serverURL + "/mcp",
+			"authorization_servers": []string{serverURL + "/tenant"},
+			"scopes_supported":      []string{"mcp:tools"},
+		})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/tenant", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                           serverURL + "/tenant",
+			"authorization_endpoint":           serverURL + "/tenant/authorize",
+			"token_endpoint":                   serverURL + "/tenant/token",
+			"registration_endpoint":            serverURL + "/tenant/register",
+			"revocation_endpoint":              serverURL + "/tenant/revoke",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/tenant/register", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RedirectURIs []string `json:"redirect_uris"`
+			AuthMethod   string   `json:"token_endpoint_auth_method"`
+			Scope        string   `json:"scope"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Decode() error = %v", err)
+		}
+		if !reflect.DeepEqual(req.RedirectURIs, []string{"http://127.0.0.1:8400/oauth/callback"}) || req.AuthMethod != "none" || req.Scope != "mcp:tools mcp:read" {
+			t.Errorf("unexpected registration request %+v", req)
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"client_id": "registered-client"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL = server.URL
+
+	d, err := Discover(context.Background(), server.URL+"/mcp")
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if d.Resource != server.URL+"/mcp" {
+		t.Errorf("Resource = %q", d.Resource)
+	}
+	if !reflect.DeepEqual(d.Scopes, []string{"mcp:tools", "mcp:read"}) {
+		t.Errorf("Scopes = %v, want the challenge's scopes", d.Scopes)
+	}
+
+	p := d.Provider()
+	if p.TokenURL != server.URL+"/tenant/token" || p.RevokeURL != server.URL+"/tenant/revoke" {
+		t.Errorf("Provider() = %+v", p)
+	}
+
+	authURL, err := url.Parse(p.AuthCodeURL("client", "http://127.0.0.1:8400/oauth/callback", "mcp:tools", "st", "ch"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := authURL.Query().Get("resource"); got != server.URL+"/mcp" {
+		t.Errorf("resource parameter = %q", got)
+	}
+
+	reg, err := d.Register(context.Background(), "http://127.0.0.1:8400/oauth/callback")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if reg.ClientID != "registered-client" || reg.ClientAuth() != ClientAuthBody {
+		t.Errorf("Register() = %+v", reg)
+	}
+}
+
+func TestDiscoverLegacyServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	d, err := Discover(context.Background(), server.URL+"/mcp/")
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if d.Resource != server.URL+"/mcp" {
+		t.Errorf("Resource = %q, want the canonical server URL", d.Resource)
+	}
+	if d.AuthServer.AuthorizationEndpoint != server.URL+"/authorize" || d.AuthServer.RegistrationEndpoint != server.URL+"/register" {
+		t.Errorf("AuthServer = %+v, want default endpoints on the server", d.AuthServer)
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	got := parseAuthParams(`realm="mcp", error=invalid_token, error_description="say \"hi\", please", scope="a b"`)
+	want := map[string]string{
+		"realm":             "mcp",
+		"error":             "invalid_token",
+		"error_description": `say "hi", please`,
+		"scope":             "a b",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAuthParams() = %v, want %v", got, want)
+	}
+}
@@ -1,12 +1,13 @@
 package oauth
 
 import (
+	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
+	"net"
 	"net/url"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -18,36 +19,88 @@ import (
 
 // TokenResponse represents the OAuth token response
 type TokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int    `json:"expires_in"`
-	TokenType    string `json:"token_type"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresIn    expiresIn `json:"expires_in"`
+	TokenType    string    `json:"token_type"`
+	// Scope lists the granted scopes; providers may omit it when they granted what was requested
+	Scope string `json:"scope"`
+	HubID int64  `json:"hub_id"`
+}
+
+// grantedScopes returns the scopes the response granted, or the requested ones if it doesn't say
+func (r *TokenResponse) grantedScopes(requested string) []string {
+	if r.Scope != "" {
+		return strings.Fields(strings.ReplaceAll(r.Scope, ",", " "))
+	}
+	return strings.Fields(strings.ReplaceAll(requested, ",", " "))
 }
 
 // AuthFlow handles the OAuth 2.0 authorization flow
 type AuthFlow struct {
-	cfg           *config.Config
-	storage       *storage.TokenStorage
-	callbackChan  chan string
-	server        *http.Server
+	cfg         *config.Config
+	provider    *Provider
+	storage     *storage.TokenStorage
+	redirectURI string
+}
+
+// Provider returns the OAuth provider the flow talks to
+func (f *AuthFlow) Provider() *Provider {
+	return f.provider
 }
 
 // NewAuthFlow creates a new OAuth flow handler
 func NewAuthFlow(cfg *config.Config) (*AuthFlow, error) {
-	store, err := storage.NewTokenStorage()
+	store, err := storage.NewProfileTokenStorage(cfg.Profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token storage: %w", err)
 	}
 
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &AuthFlow{
-		cfg:          cfg,
-		storage:      store,
-		callbackChan: make(chan string, 1),
+		cfg:         cfg,
+		provider:    provider,
+		storage:     store,
+		redirectURI: cfg.HubSpot.RedirectURI,
 	}, nil
 }
 
+// LoginOptions controls how the authorization code reaches mission-control
+type LoginOptions struct {
+	// NoBrowser skips opening a browser and reads the pasted redirect URL or code from Input
+	NoBrowser bool
+	// ListenAddr binds the callback server to this address (e.g. a forwarded port)
+	// instead of the port in the redirect URI
+	ListenAddr string
+	// Input is read for the pasted redirect URL in NoBrowser mode (default: os.Stdin)
+	Input io.Reader
+	// Output receives the login instructions (default: os.Stderr)
+	Output io.Writer
+}
+
 // Login initiates the OAuth login flow
 func (f *AuthFlow) Login(ctx context.Context) error {
+	return f.LoginWithOptions(ctx, LoginOptions{})
+}
+
+// LoginWithOptions runs the OAuth login flow. The authorization code arrives either on the
+// local callback server or, in NoBrowser mode, as a redirect URL pasted on Input.
+func (f *AuthFlow) LoginWithOptions(ctx context.Context, opts LoginOptions) error {
+	if err := f.cfg.HubSpot.RequireClientID(); err != nil {
+		return err
+	}
+
+	if opts.Input == nil {
+		opts.Input = os.Stdin
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stderr
+	}
+
 	// Generate PKCE parameters
 	verifier, err := GenerateCodeVerifier()
 	if err != nil {
@@ -60,29 +113,59 @@ func (f *AuthFlow) Login(ctx context.Context) error {
 		return fmt.Errorf("failed to generate state: %w", err)
 	}
 
+	// Start callback server, unless the code will only be pasted
+	var callbackResults <-chan CallbackResult
+	if !opts.NoBrowser || opts.ListenAddr != "" {
+		callback, err := f.startCallbackServer(opts.ListenAddr, state)
+		if err != nil {
+			return fmt.Errorf("failed to start callback server: %w", err)
+		}
+		defer callback.Close()
+		callbackResults = callback.Results()
+	}
+
 	// Build authorization URL
 	authURL := f.buildAuthURL(challenge, state)
-	logging.Info("Opening browser for authorization...")
-	logging.Info("Please visit: %s", authURL)
-
-	// Start callback server
-	port := f.getCallbackPort()
-	if err := f.startCallbackServer(port, state); err != nil {
-		return fmt.Errorf("failed to start callback server: %w", err)
-	}
-	defer f.stopCallbackServer()
 
-	// Open browser
-	if err := openBrowser(authURL); err != nil {
-		logging.Error("Failed to open browser automatically: %v", err)
-		logging.Info("Please open the URL manually in your browser")
+	pasted := make(chan string, 1)
+	pasteErrs := make(chan error, 1)
+	if opts.NoBrowser {
+		fmt.Fprintf(opts.Output, "Open this URL in a browser on any machine:\n\n  %s\n\n", authURL)
+		fmt.Fprintf(opts.Output, "After approving, the browser is redirected to %s.\n", f.redirectURI)
+		fmt.Fprintln(opts.Output, "If that page fails to load, copy the full URL from the address bar and paste it here (or just the code):")
+
+		go func() {
+			code, err := readPastedCode(opts.Input, state)
+			if err != nil {
+				pasteErrs <- err
+				return
+			}
+			pasted <- code
+		}()
+	} else {
+		// The URL goes to Output rather than the logs, which may be in a file or filtered out
+		fmt.Fprintf(opts.Output, "Opening browser for authorization...\nPlease visit: %s\n", authURL)
+
+		// Open browser
+		if err := openBrowser(authURL); err != nil {
+			logging.Error("Failed to open browser automatically: %v", err)
+			fmt.Fprintln(opts.Output, "Please open the URL manually in your browser")
+		}
 	}
 
 	// Wait for callback
 	select {
-	case code := <-f.callbackChan:
+	case result := <-callbackResults:
+		if result.Err != nil {
+			return result.Err
+		}
+		logging.Info("Authorization code received")
+		return f.exchangeCodeForToken(ctx, result.Code, verifier)
+	case code := <-pasted:
 		logging.Info("Authorization code received")
-		return f.exchangeCodeForToken(code, verifier)
+		return f.exchangeCodeForToken(ctx, code, verifier)
+	case err := <-pasteErrs:
+		return err
 	case <-ctx.Done():
 		return fmt.Errorf("login canceled")
 	case <-time.After(5 * time.Minute):
@@ -90,48 +173,108 @@ func (f *AuthFlow) Login(ctx context.Context) error {
 	}
 }
 
-// RefreshToken refreshes the access token using the refresh token
-func (f *AuthFlow) RefreshToken(ctx context.Context) error {
-	token, err := f.storage.LoadToken()
+// readPastedCode reads one line from r and extracts the authorization code from it
+func readPastedCode(r io.Reader, expectedState string) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read redirect URL: %w", err)
+	}
+	return parseRedirectInput(line, expectedState)
+}
+
+// parseRedirectInput accepts a full redirect URL, its query string, or a bare code.
+// When a state is present it must match the one sent in the authorization request.
+func parseRedirectInput(input, expectedState string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", fmt.Errorf("no redirect URL or code entered")
+	}
+
+	if !strings.Contains(input, "code=") && !strings.Contains(input, "error=") {
+		// A bare code; PKCE still binds it to this login attempt
+		return input, nil
+	}
+
+	query := input
+	if i := strings.Index(input, "?"); i >= 0 {
+		query = input[i+1:]
+	}
+	if i := strings.Index(query, "#"); i >= 0 {
+		query = query[:i]
+	}
+
+	values, err := url.ParseQuery(query)
 	if err != nil {
-		return fmt.Errorf("failed to load token: %w", err)
+		return "", fmt.Errorf("invalid redirect URL: %w", err)
 	}
 
-	if token.RefreshToken == "" {
-		return fmt.Errorf("no refresh token available")
+	if errorParam := values.Get("error"); errorParam != "" {
+		return "", fmt.Errorf("authorization failed: %s %s", errorParam, values.Get("error_description"))
 	}
 
-	data := url.Values{}
-	data.Set("grant_type", "refresh_token")
-	data.Set("client_id", f.cfg.HubSpot.ClientID)
-	data.Set("refresh_token", token.RefreshToken)
+	if values.Get("state") != expectedState {
+		return "", fmt.Errorf("invalid state parameter - the URL does not belong to this login attempt")
+	}
 
-	if f.cfg.HubSpot.ClientSecret != "" {
-		data.Set("client_secret", f.cfg.HubSpot.ClientSecret)
+	code := values.Get("code")
+	if code == "" {
+		return "", fmt.Errorf("no authorization code in redirect URL")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", config.HubSpotTokenURL, strings.NewReader(data.Encode()))
+	return code, nil
+}
+
+// refreshLockTimeout bounds the wait for another process's refresh to finish
+const refreshLockTimeout = 60 * time.Second
+
+// RefreshToken refreshes the access token using the refresh token. Refreshes of the
+// same stored token are serialized across goroutines and processes; a caller that
+// finds the token already refreshed while it waited reuses the new token, so refresh
+// token rotation never invalidates a concurrent refresh.
+func (f *AuthFlow) RefreshToken(ctx context.Context) error {
+	token, err := f.storage.LoadToken()
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to load token: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token.IsPrivateApp() {
+		return fmt.Errorf("private app tokens do not expire and cannot be refreshed")
+	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	lockCtx, cancel := context.WithTimeout(ctx, refreshLockTimeout)
+	defer cancel()
+	unlock, err := f.storage.Lock(lockCtx)
 	if err != nil {
-		return fmt.Errorf("failed to refresh token: %w", err)
+		return fmt.Errorf("failed to acquire refresh lock: %w", err)
+	}
+	defer unlock()
+
+	// Re-read under the lock: another refresh may have finished while we waited
+	seen := token.AccessToken
+	token, err = f.storage.LoadToken()
+	if err != nil {
+		return fmt.Errorf("failed to load token: %w", err)
+	}
+	if token.AccessToken != seen && !token.IsExpiringSoon(time.Minute) {
+		logging.Debug("Token was already refreshed by another process")
+		return nil
+	}
+
+	if token.RefreshToken == "" {
+		return fmt.Errorf("no refresh token available")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("token refresh failed with status %d: %s", resp.StatusCode, string(body))
+	if err := f.cfg.HubSpot.RequireClientID(); err != nil {
+		return err
 	}
 
-	var tokenResp TokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return fmt.Errorf("failed to decode token response: %w", err)
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", token.RefreshToken)
+
+	tokenResp, err := f.provider.Exchange(ctx, f.cfg.HubSpot.ClientID, f.cfg.HubSpot.ClientSecret, data)
+	if err != nil {
+		return fmt.Errorf("failed to refresh token: %w", err)
 	}
 
 	// Update stored token
@@ -140,6 +283,11 @@ func (f *AuthFlow) RefreshToken(ctx context.Context) error {
 		token.RefreshToken = tokenResp.RefreshToken
 	}
 	token.ExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	token.RefreshedAt = time.Now()
+	token.RefreshCount++
+	if tokenResp.Scope != "" {
+		token.Scopes = tokenResp.grantedScopes("")
+	}
 
 	if err := f.storage.SaveToken(token); err != nil {
 		return fmt.Errorf("failed to save refreshed token: %w", err)
@@ -149,121 +297,82 @@ func (f *AuthFlow) RefreshToken(ctx context.Context) error {
 	return nil
 }
 
-func (f *AuthFlow) buildAuthURL(challenge, state string) string {
-	params := url.Values{}
-	params.Set("client_id", f.cfg.HubSpot.ClientID)
-	params.Set("redirect_uri", f.cfg.HubSpot.RedirectURI)
-	params.Set("scope", f.cfg.HubSpot.Scopes)
-	params.Set("state", state)
-	params.Set("code_challenge", challenge)
-	params.Set("code_challenge_method", "S256")
-
-	return config.HubSpotAuthURL + "?" + params.Encode()
+// RevokeToken revokes a refresh token with the provider so it can no longer be used.
+// A token the provider no longer knows about is treated as already revoked.
+func (f *AuthFlow) RevokeToken(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return fmt.Errorf("no refresh token to revoke")
+	}
+
+	return f.provider.Revoke(ctx, f.cfg.HubSpot.ClientID, f.cfg.HubSpot.ClientSecret, refreshToken)
 }
 
-func (f *AuthFlow) startCallbackServer(port, expectedState string) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/oauth/callback", func(w http.ResponseWriter, r *http.Request) {
-		state := r.URL.Query().Get("state")
-		code := r.URL.Query().Get("code")
-		errorParam := r.URL.Query().Get("error")
-
-		if errorParam != "" {
-			http.Error(w, "Authorization failed: "+errorParam, http.StatusBadRequest)
-			close(f.callbackChan)
-			return
-		}
+func (f *AuthFlow) buildAuthURL(challenge, state string) string {
+	return f.provider.AuthCodeURL(f.cfg.HubSpot.ClientID, f.redirectURI, f.cfg.HubSpot.Scopes, state, challenge)
+}
 
-		if state != expectedState {
-			http.Error(w, "Invalid state parameter", http.StatusBadRequest)
-			close(f.callbackChan)
-			return
-		}
+// startCallbackServer binds the callback server on listenAddr, or on the redirect URI's
+// loopback host and port. When the redirect URI uses port 0, it is rewritten to the bound port.
+func (f *AuthFlow) startCallbackServer(listenAddr, expectedState string) (*CallbackServer, error) {
+	u, err := url.Parse(f.cfg.HubSpot.RedirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URI: %w", err)
+	}
 
-		if code == "" {
-			http.Error(w, "No authorization code received", http.StatusBadRequest)
-			close(f.callbackChan)
-			return
+	addr := net.JoinHostPort(u.Hostname(), f.getCallbackPort())
+	if listenAddr != "" {
+		addr = listenAddr
+		if !strings.Contains(addr, ":") {
+			addr = net.JoinHostPort(u.Hostname(), addr)
 		}
+	}
 
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
-			<html>
-			<body>
-				<h1>Authorization Successful!</h1>
-				<p>You can close this window and return to the terminal.</p>
-			</body>
-			</html>
-		`)
-
-		f.callbackChan <- code
-	})
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
 
-	f.server = &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
+	callback, err := NewCallbackServer(addr, path, expectedState)
+	if err != nil {
+		return nil, err
 	}
 
-	go func() {
-		if err := f.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logging.Error("Callback server error: %v", err)
+	f.redirectURI = f.cfg.HubSpot.RedirectURI
+	if u.Port() == "0" {
+		f.redirectURI, err = withPort(f.cfg.HubSpot.RedirectURI, callback.Port())
+		if err != nil {
+			callback.Close()
+			return nil, err
 		}
-	}()
-
-	// Give server time to start
-	time.Sleep(100 * time.Millisecond)
-	return nil
-}
-
-func (f *AuthFlow) stopCallbackServer() {
-	if f.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		f.server.Shutdown(ctx)
 	}
+
+	return callback, nil
 }
 
-func (f *AuthFlow) exchangeCodeForToken(code, verifier string) error {
+func (f *AuthFlow) exchangeCodeForToken(ctx context.Context, code, verifier string) error {
 	data := url.Values{}
 	data.Set("grant_type", "authorization_code")
-	data.Set("client_id", f.cfg.HubSpot.ClientID)
-	data.Set("redirect_uri", f.cfg.HubSpot.RedirectURI)
+	data.Set("redirect_uri", f.redirectURI)
 	data.Set("code", code)
 	data.Set("code_verifier", verifier)
 
-	if f.cfg.HubSpot.ClientSecret != "" {
-		data.Set("client_secret", f.cfg.HubSpot.ClientSecret)
-	}
-
-	req, err := http.NewRequest("POST", config.HubSpotTokenURL, strings.NewReader(data.Encode()))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	tokenResp, err := f.provider.Exchange(ctx, f.cfg.HubSpot.ClientID, f.cfg.HubSpot.ClientSecret, data)
 	if err != nil {
 		return fmt.Errorf("failed to exchange code for token: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("token exchange failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var tokenResp TokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return fmt.Errorf("failed to decode token response: %w", err)
-	}
 
 	// Save token
+	now := time.Now()
 	token := &storage.Token{
 		AccessToken:  tokenResp.AccessToken,
 		RefreshToken: tokenResp.RefreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+		ExpiresAt:    now.Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+		Type:         storage.TokenTypeOAuth,
+		Scopes:       tokenResp.grantedScopes(f.cfg.HubSpot.Scopes),
+		HubID:        tokenResp.HubID,
+		ClientID:     f.cfg.HubSpot.ClientID,
+		ServerURL:    f.cfg.MCP.URL,
+		ObtainedAt:   now,
 	}
 
 	if err := f.storage.SaveToken(token); err != nil {
@@ -0,0 +1,45 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRedirectInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"full URL", "http://127.0.0.1:8400/oauth/callback?code=abc123&state=xyz\n", "abc123", false},
+		{"query string", "code=abc123&state=xyz", "abc123", false},
+		{"bare code", "  abc123  ", "abc123", false},
+		{"state mismatch", "http://127.0.0.1:8400/oauth/callback?code=abc123&state=other", "", true},
+		{"missing state", "http://127.0.0.1:8400/oauth/callback?code=abc123", "", true},
+		{"authorization error", "http://127.0.0.1:8400/oauth/callback?error=access_denied&state=xyz", "", true},
+		{"empty", "\n", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRedirectInput(tt.input, "xyz")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRedirectInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRedirectInput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPastedCode(t *testing.T) {
+	code, err := readPastedCode(strings.NewReader("http://127.0.0.1:8400/oauth/callback?code=pasted&state=s1\nextra"), "s1")
+	if err != nil {
+		t.Fatalf("readPastedCode() error = %v", err)
+	}
+	if code != "pasted" {
+		t.Errorf("readPastedCode() = %q, want pasted", code)
+	}
+}
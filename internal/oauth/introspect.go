@@ -0,0 +1,150 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/launch01/mission-control/internal/storage"
+)
+
+// accessTokenInfo is HubSpot's description of an OAuth access token
+type accessTokenInfo struct {
+	HubID     int64    `json:"hub_id"`
+	HubDomain string   `json:"hub_domain"`
+	User      string   `json:"user"`
+	UserID    int64    `json:"user_id"`
+	AppID     int64    `json:"app_id"`
+	Scopes    []string `json:"scopes"`
+}
+
+// privateAppTokenInfo is HubSpot's description of a private app access token
+type privateAppTokenInfo struct {
+	HubID  int64    `json:"hubId"`
+	UserID int64    `json:"userId"`
+	AppID  int64    `json:"appId"`
+	Scopes []string `json:"scopes"`
+}
+
+// Introspect asks HubSpot which portal, user and app the token belongs to and which scopes it grants
+func (f *AuthFlow) Introspect(ctx context.Context, token *storage.Token) (*storage.TokenInfo, error) {
+	if token.IsPrivateApp() {
+		return f.introspectPrivateApp(ctx, token.AccessToken)
+	}
+
+	if f.provider.AccessTokenInfoURL == "" {
+		return nil, fmt.Errorf("OAuth provider %s does not describe tokens", f.provider.Name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", f.provider.AccessTokenInfoURL+"/"+url.PathEscape(token.AccessToken), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var info accessTokenInfo
+	if err := doIntrospect(req, &info); err != nil {
+		return nil, err
+	}
+
+	return &storage.TokenInfo{
+		HubID:     info.HubID,
+		HubDomain: info.HubDomain,
+		User:      info.User,
+		UserID:    info.UserID,
+		AppID:     info.AppID,
+		Scopes:    info.Scopes,
+		FetchedAt: time.Now(),
+	}, nil
+}
+
+func (f *AuthFlow) introspectPrivateApp(ctx context.Context, accessToken string) (*storage.TokenInfo, error) {
+	if f.provider.PrivateAppTokenInfoURL == "" {
+		return nil, fmt.Errorf("OAuth provider %s does not describe private app tokens", f.provider.Name)
+	}
+
+	body, err := json.Marshal(map[string]string{"tokenKey": accessToken})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", f.provider.PrivateAppTokenInfoURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var info privateAppTokenInfo
+	if err := doIntrospect(req, &info); err != nil {
+		return nil, err
+	}
+
+	return &storage.TokenInfo{
+		HubID:     info.HubID,
+		UserID:    info.UserID,
+		AppID:     info.AppID,
+		Scopes:    info.Scopes,
+		FetchedAt: time.Now(),
+	}, nil
+}
+
+func doIntrospect(req *http.Request, v interface{}) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch token info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("token info request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode token info: %w", err)
+	}
+	return nil
+}
+
+// MissingScopes returns the space-separated requested scopes that are not in granted
+func MissingScopes(requested string, granted []string) []string {
+	have := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		have[scope] = true
+	}
+
+	var missing []string
+	for _, scope := range strings.Fields(requested) {
+		if !have[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// MergeScopes returns the space-separated scopes in existing followed by any
+// scopes in add that are not already present
+func MergeScopes(existing string, add ...string) string {
+	scopes := strings.Fields(existing)
+	have := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		have[scope] = true
+	}
+
+	for _, scope := range add {
+		for _, s := range strings.Fields(strings.ReplaceAll(scope, ",", " ")) {
+			if !have[s] {
+				have[s] = true
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	return strings.Join(scopes, " ")
+}
@@ -0,0 +1,86 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/launch01/mission-control/internal/config"
+	"github.com/launch01/mission-control/internal/storage"
+)
+
+func TestIntrospect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/v1/access-tokens/oauth-token":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"hub_id":     12345,
+				"hub_domain": "example.hubspot.com",
+				"user":       "dev@example.com",
+				"user_id":    42,
+				"app_id":     777,
+				"scopes":     []string{"oauth", "crm.objects.contacts.read"},
+			})
+		case "/oauth/v2/private-apps/get/access-token-info":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["tokenKey"] != "pat-token" {
+				t.Errorf("unexpected private app request body %v (%v)", body, err)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"hubId":  12345,
+				"userId": 42,
+				"appId":  888,
+				"scopes": []string{"crm.objects.deals.read"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	flow := &AuthFlow{provider: HubSpotProvider(config.DefaultHubSpotAuthURL, server.URL)}
+
+	info, err := flow.Introspect(context.Background(), &storage.Token{AccessToken: "oauth-token"})
+	if err != nil {
+		t.Fatalf("Introspect() error = %v", err)
+	}
+	if info.HubID != 12345 || info.HubDomain != "example.hubspot.com" || info.User != "dev@example.com" || info.AppID != 777 {
+		t.Errorf("Introspect() = %+v", info)
+	}
+	if info.FetchedAt.IsZero() {
+		t.Error("FetchedAt should be set")
+	}
+
+	info, err = flow.Introspect(context.Background(), &storage.Token{AccessToken: "pat-token", Type: storage.TokenTypePrivateApp})
+	if err != nil {
+		t.Fatalf("Introspect() private app error = %v", err)
+	}
+	if info.HubID != 12345 || info.AppID != 888 || !reflect.DeepEqual(info.Scopes, []string{"crm.objects.deals.read"}) {
+		t.Errorf("Introspect() private app = %+v", info)
+	}
+
+	if _, err := flow.Introspect(context.Background(), &storage.Token{AccessToken: "unknown"}); err == nil {
+		t.Error("Introspect() should fail for an unknown token")
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	got := MissingScopes("crm.objects.contacts.read  crm.objects.deals.write", []string{"oauth", "crm.objects.contacts.read"})
+	if want := []string{"crm.objects.deals.write"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingScopes() = %v, want %v", got, want)
+	}
+
+	if got := MissingScopes("crm.objects.contacts.read", []string{"crm.objects.contacts.read"}); len(got) != 0 {
+		t.Errorf("MissingScopes() = %v, want none", got)
+	}
+}
+
+func TestMergeScopes(t *testing.T) {
+	got := MergeScopes("crm.objects.contacts.read oauth", "crm.objects.tickets.read,oauth", "crm.objects.custom.read crm.objects.contacts.read")
+	if want := "crm.objects.contacts.read oauth crm.objects.tickets.read crm.objects.custom.read"; got != want {
+		t.Errorf("MergeScopes() = %q, want %q", got, want)
+	}
+}
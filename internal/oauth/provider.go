@@ -0,0 +1,296 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/launch01/mission-control/internal/config"
+)
+
+// Provider presets selectable with MISSION_CONTROL_OAUTH_PROVIDER
+const (
+	ProviderHubSpot    = "hubspot"
+	ProviderHubSpotEU1 = "hubspot-eu1"
+	ProviderCustom     = "custom"
+)
+
+// Ways a provider revokes refresh tokens
+const (
+	// RevokeHubSpot sends DELETE {RevokeURL}/{token}
+	RevokeHubSpot = "hubspot"
+	// RevokeRFC7009 POSTs token=... to RevokeURL as in RFC 7009
+	RevokeRFC7009 = "rfc7009"
+)
+
+// Ways the client authenticates to the token endpoint
+const (
+	// ClientAuthBody sends client_id and client_secret as form fields
+	ClientAuthBody = "body"
+	// ClientAuthBasic sends them as HTTP basic credentials
+	ClientAuthBasic = "basic"
+)
+
+// Provider describes an OAuth 2.0 authorization server and its quirks
+type Provider struct {
+	Name     string
+	AuthURL  string
+	TokenURL string
+
+	// RevokeURL revokes refresh tokens; empty when the provider can't revoke
+	RevokeURL   string
+	RevokeStyle string
+
+	// AccessTokenInfoURL and PrivateAppTokenInfoURL describe tokens; HubSpot only
+	AccessTokenInfoURL     string
+	PrivateAppTokenInfoURL string
+
+	// AuthParams are added to every authorization request
+	AuthParams map[string]string
+	// ScopeSeparator joins scopes in the authorization request (default: space)
+	ScopeSeparator string
+	// ClientAuth is ClientAuthBody (default) or ClientAuthBasic
+	ClientAuth string
+	// DefaultExpiresIn is assumed when a token response has no expires_in
+	DefaultExpiresIn time.Duration
+	// Resource is the RFC 8707 resource indicator naming the MCP server the token is for
+	Resource string
+}
+
+// HubSpotProvider returns the HubSpot preset for the given authorization page and API base URL
+func HubSpotProvider(authURL, apiBaseURL string) *Provider {
+	apiBaseURL = strings.TrimRight(apiBaseURL, "/")
+	return &Provider{
+		Name:                   ProviderHubSpot,
+		AuthURL:                authURL,
+		TokenURL:               apiBaseURL + "/oauth/v1/token",
+		RevokeURL:              apiBaseURL + "/oauth/v1/refresh-tokens",
+		RevokeStyle:            RevokeHubSpot,
+		AccessTokenInfoURL:     apiBaseURL + "/oauth/v1/access-tokens",
+		PrivateAppTokenInfoURL: apiBaseURL + "/oauth/v2/private-apps/get/access-token-info",
+		ScopeSeparator:         " ",
+		ClientAuth:             ClientAuthBody,
+		DefaultExpiresIn:       30 * time.Minute,
+	}
+}
+
+// NewProvider builds the provider selected by cfg: a preset with any configured
+// overrides applied, or a custom provider defined entirely by configuration
+func NewProvider(cfg *config.Config) (*Provider, error) {
+	var p *Provider
+	switch cfg.OAuth.Provider {
+	case "", ProviderHubSpot:
+		p = HubSpotProvider(config.DefaultHubSpotAuthURL, config.DefaultAPIBaseURL)
+	case ProviderHubSpotEU1:
+		p = HubSpotProvider("https://app-eu1.hubspot.com/oauth/authorize", "https://api-eu1.hubapi.com")
+		p.Name = ProviderHubSpotEU1
+	case ProviderCustom:
+		p = &Provider{Name: ProviderCustom, RevokeStyle: RevokeRFC7009, DefaultExpiresIn: time.Hour}
+	default:
+		return nil, fmt.Errorf("unknown OAuth provider %q (use %q, %q or %q)", cfg.OAuth.Provider, ProviderHubSpot, ProviderHubSpotEU1, ProviderCustom)
+	}
+
+	// HubSpot-specific overrides, e.g. a local stand-in for tests
+	if p.RevokeStyle == RevokeHubSpot {
+		name := p.Name
+		authURL := p.AuthURL
+		if cfg.HubSpot.AuthURL != "" {
+			authURL = cfg.HubSpot.AuthURL
+		}
+		if cfg.HubSpot.APIBaseURL != "" {
+			p = HubSpotProvider(authURL, cfg.HubSpot.APIBaseURL)
+			p.Name = name
+		}
+		p.AuthURL = authURL
+	}
+
+	o := cfg.OAuth
+	if o.AuthURL != "" {
+		p.AuthURL = o.AuthURL
+	}
+	if o.TokenURL != "" {
+		p.TokenURL = o.TokenURL
+	}
+	if o.RevokeURL != "" {
+		p.RevokeURL = o.RevokeURL
+	}
+	if len(o.AuthParams) > 0 {
+		p.AuthParams = o.AuthParams
+	}
+	if o.ScopeSeparator != "" {
+		p.ScopeSeparator = o.ScopeSeparator
+	}
+	if o.ClientAuth != "" {
+		p.ClientAuth = o.ClientAuth
+	}
+	p.Resource = o.Resource
+
+	return p, p.Validate()
+}
+
+// Validate checks that the provider has the endpoints and settings the login flow needs
+func (p *Provider) Validate() error {
+	if p.AuthURL == "" || p.TokenURL == "" {
+		return fmt.Errorf("OAuth provider %s needs an authorization URL and a token URL (MISSION_CONTROL_OAUTH_AUTH_URL, MISSION_CONTROL_OAUTH_TOKEN_URL)", p.Name)
+	}
+	switch p.ClientAuth {
+	case "", ClientAuthBody, ClientAuthBasic:
+	default:
+		return fmt.Errorf("invalid OAuth client auth %q (use %q or %q)", p.ClientAuth, ClientAuthBody, ClientAuthBasic)
+	}
+	return nil
+}
+
+// AuthCodeURL builds the authorization request URL for the PKCE flow
+func (p *Provider) AuthCodeURL(clientID, redirectURI, scopes, state, challenge string) string {
+	params := url.Values{}
+	for key, value := range p.AuthParams {
+		params.Set(key, value)
+	}
+	params.Set("client_id", clientID)
+	params.Set("redirect_uri", redirectURI)
+	if scope := p.joinScopes(scopes); scope != "" {
+		params.Set("scope", scope)
+	}
+	if p.Resource != "" {
+		params.Set("resource", p.Resource)
+	}
+	params.Set("state", state)
+	params.Set("code_challenge", challenge)
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.AuthURL + sep + params.Encode()
+}
+
+// joinScopes rejoins space-separated scopes with the provider's separator
+func (p *Provider) joinScopes(scopes string) string {
+	sep := p.ScopeSeparator
+	if sep == "" {
+		sep = " "
+	}
+	return strings.Join(strings.Fields(scopes), sep)
+}
+
+// Exchange posts a token request and decodes the response
+func (p *Provider) Exchange(ctx context.Context, clientID, clientSecret string, data url.Values) (*TokenResponse, error) {
+	if p.Resource != "" {
+		data.Set("resource", p.Resource)
+	}
+	if p.ClientAuth != ClientAuthBasic {
+		data.Set("client_id", clientID)
+		if clientSecret != "" {
+			data.Set("client_secret", clientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.TokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientAuth == ClientAuthBasic {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tokenResp TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	if tokenResp.ExpiresIn == 0 {
+		tokenResp.ExpiresIn = expiresIn(p.DefaultExpiresIn.Seconds())
+	}
+
+	return &tokenResp, nil
+}
+
+// Revoke revokes a refresh token. A token the provider no longer knows about is
+// treated as already revoked.
+func (p *Provider) Revoke(ctx context.Context, clientID, clientSecret, refreshToken string) error {
+	if p.RevokeURL == "" {
+		return fmt.Errorf("OAuth provider %s has no revocation endpoint", p.Name)
+	}
+
+	var req *http.Request
+	var err error
+	if p.RevokeStyle == RevokeHubSpot {
+		req, err = http.NewRequestWithContext(ctx, "DELETE", p.RevokeURL+"/"+url.PathEscape(refreshToken), nil)
+	} else {
+		data := url.Values{}
+		data.Set("token", refreshToken)
+		data.Set("token_type_hint", "refresh_token")
+		if p.ClientAuth != ClientAuthBasic {
+			data.Set("client_id", clientID)
+			if clientSecret != "" {
+				data.Set("client_secret", clientSecret)
+			}
+		}
+		req, err = http.NewRequestWithContext(ctx, "POST", p.RevokeURL, strings.NewReader(data.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if p.ClientAuth == ClientAuthBasic {
+				req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("token revocation failed with status %d: %s", resp.StatusCode, string(body))
+}
+
+// expiresIn is a token lifetime in seconds that some providers send as a string
+type expiresIn int
+
+func (e *expiresIn) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*e = 0
+		return nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expires_in %s", string(data))
+	}
+	*e = expiresIn(n)
+	return nil
+}
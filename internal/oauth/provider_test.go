@@ -0,0 +1,121 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launch01/mission-control/internal/config"
+)
+
+func TestNewProvider(t *testing.T) {
+	p, err := NewProvider(&config.Config{})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if p.Name != ProviderHubSpot || p.TokenURL != "https://api.hubapi.com/oauth/v1/token" {
+		t.Errorf("default provider = %+v", p)
+	}
+
+	p, err = NewProvider(&config.Config{OAuth: config.OAuthConfig{Provider: ProviderHubSpotEU1}})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if p.AuthURL != "https://app-eu1.hubspot.com/oauth/authorize" || p.RevokeURL != "https://api-eu1.hubapi.com/oauth/v1/refresh-tokens" {
+		t.Errorf("EU provider = %+v", p)
+	}
+
+	p, err = NewProvider(&config.Config{HubSpot: config.HubSpotConfig{APIBaseURL: "http://127.0.0.1:9999/"}})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if p.TokenURL != "http://127.0.0.1:9999/oauth/v1/token" || p.AuthURL != config.DefaultHubSpotAuthURL {
+		t.Errorf("API base override = %+v", p)
+	}
+
+	if _, err := NewProvider(&config.Config{OAuth: config.OAuthConfig{Provider: ProviderCustom}}); err == nil {
+		t.Error("custom provider without URLs should fail")
+	}
+	if _, err := NewProvider(&config.Config{OAuth: config.OAuthConfig{Provider: "acme"}}); err == nil {
+		t.Error("unknown provider should fail")
+	}
+}
+
+func TestProviderAuthCodeURL(t *testing.T) {
+	p := &Provider{
+		AuthURL:        "https://auth.example.com/authorize?tenant=t1",
+		AuthParams:     map[string]string{"prompt": "consent"},
+		ScopeSeparator: ",",
+	}
+
+	u, err := url.Parse(p.AuthCodeURL("client", "http://127.0.0.1:8400/cb", "read  write", "st", "ch"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	q := u.Query()
+	if q.Get("tenant") != "t1" || q.Get("prompt") != "consent" || q.Get("scope") != "read,write" || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("AuthCodeURL() query = %v", q)
+	}
+}
+
+func TestProviderExchangeQuirks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "client" || pass != "secret" {
+			t.Errorf("expected basic client auth, got %q %q %v", user, pass, ok)
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("client_secret") != "" {
+			t.Errorf("client secret should not be in the body: %v", r.PostForm)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("grant_type") == "refresh_token" {
+			w.Write([]byte(`{"access_token":"a2"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"a1","refresh_token":"r1","expires_in":"3600"}`))
+	}))
+	defer server.Close()
+
+	p := &Provider{Name: ProviderCustom, TokenURL: server.URL, ClientAuth: ClientAuthBasic, DefaultExpiresIn: 10 * time.Minute}
+
+	resp, err := p.Exchange(context.Background(), "client", "secret", url.Values{"grant_type": {"authorization_code"}})
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if resp.AccessToken != "a1" || resp.ExpiresIn != 3600 {
+		t.Errorf("Exchange() = %+v", resp)
+	}
+
+	resp, err = p.Exchange(context.Background(), "client", "secret", url.Values{"grant_type": {"refresh_token"}})
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if resp.ExpiresIn != 600 {
+		t.Errorf("missing expires_in should use the default, got %d", resp.ExpiresIn)
+	}
+}
+
+func TestProviderRevokeRFC7009(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm() error = %v", err)
+		}
+		if r.Method != "POST" || r.PostForm.Get("token") != "refresh-1" || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			t.Errorf("unexpected revocation request %s %v", r.Method, r.PostForm)
+		}
+	}))
+	defer server.Close()
+
+	p := &Provider{Name: ProviderCustom, RevokeURL: server.URL, RevokeStyle: RevokeRFC7009}
+	if err := p.Revoke(context.Background(), "client", "", "refresh-1"); err != nil {
+		t.Errorf("Revoke() error = %v", err)
+	}
+
+	if err := (&Provider{Name: ProviderCustom}).Revoke(context.Background(), "client", "", "refresh-1"); err == nil {
+		t.Error("Revoke() without an endpoint should fail")
+	}
+}
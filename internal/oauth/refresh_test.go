@@ -0,0 +1,79 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/launch01/mission-control/internal/config"
+	"github.com/launch01/mission-control/internal/storage"
+)
+
+func TestConcurrentRefreshIsSingleFlight(t *testing.T) {
+	var refreshes atomic.Int32
+	var mu sync.Mutex
+	validRefresh := "refresh-0"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Rotation: each refresh token works once
+		if r.PostForm.Get("refresh_token") != validRefresh {
+			http.Error(w, `{"status":"BAD_REFRESH_TOKEN"}`, http.StatusBadRequest)
+			return
+		}
+		n := refreshes.Add(1)
+		validRefresh = fmt.Sprintf("refresh-%d", n)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("access-%d", n),
+			"refresh_token": validRefresh,
+			"expires_in":    1800,
+		})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token.json")
+	expired := &storage.Token{AccessToken: "access-0", RefreshToken: "refresh-0", ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := storage.NewFileTokenStorage(path).SaveToken(expired); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	cfg := &config.Config{HubSpot: config.HubSpotConfig{ClientID: "client"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A separate flow and storage per caller, as in separate processes
+			flow := &AuthFlow{cfg: cfg, provider: &Provider{TokenURL: server.URL}, storage: storage.NewFileTokenStorage(path)}
+			if err := flow.RefreshToken(context.Background()); err != nil {
+				t.Errorf("RefreshToken() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("token endpoint called %d times, want 1", n)
+	}
+
+	token, err := storage.NewFileTokenStorage(path).LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" {
+		t.Errorf("stored token = %+v, want the single refresh's result", token)
+	}
+	if token.RefreshCount != 1 || token.RefreshedAt.IsZero() {
+		t.Errorf("RefreshCount = %d, RefreshedAt = %v, want one recorded refresh", token.RefreshCount, token.RefreshedAt)
+	}
+}
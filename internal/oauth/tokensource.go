@@ -0,0 +1,207 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/launch01/mission-control/internal/logging"
+	"github.com/launch01/mission-control/internal/storage"
+)
+
+// DefaultRefreshLead is how long before expiry a TokenSource refreshes the token
+const DefaultRefreshLead = 10 * time.Minute
+
+const (
+	// defaultPollInterval is how often the stored token is re-read to pick up
+	// tokens refreshed or replaced by other processes
+	defaultPollInterval = time.Minute
+	// defaultMinBackoff and defaultMaxBackoff bound the wait between failed refreshes
+	defaultMinBackoff = 5 * time.Second
+	defaultMaxBackoff = 5 * time.Minute
+)
+
+// Health describes the state of a TokenSource
+type Health struct {
+	// Healthy means a token is available and not expired
+	Healthy             bool      `json:"healthy"`
+	CredentialType      string    `json:"credential_type,omitempty"`
+	ExpiresAt           time.Time `json:"expires_at"`
+	NextRefresh         time.Time `json:"next_refresh"`
+	LastRefresh         time.Time `json:"last_refresh"`
+	LastError           string    `json:"last_error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+}
+
+// TokenSource keeps the stored token fresh in the background. It refreshes ahead of
+// expiry, backs off exponentially when refreshing fails, and tells subscribers about
+// every new token, including tokens refreshed by other processes.
+type TokenSource struct {
+	flow         *AuthFlow
+	lead         time.Duration
+	pollInterval time.Duration
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+
+	mu          sync.Mutex
+	token       *storage.Token
+	subscribers []func(*storage.Token)
+	health      Health
+}
+
+// NewTokenSource creates a token source that refreshes lead ahead of expiry
+func NewTokenSource(flow *AuthFlow, lead time.Duration) *TokenSource {
+	if lead <= 0 {
+		lead = DefaultRefreshLead
+	}
+	return &TokenSource{
+		flow:         flow,
+		lead:         lead,
+		pollInterval: defaultPollInterval,
+		minBackoff:   defaultMinBackoff,
+		maxBackoff:   defaultMaxBackoff,
+	}
+}
+
+// Subscribe registers fn to be called with each new token. If a token is already
+// loaded, fn is called with it immediately.
+func (s *TokenSource) Subscribe(fn func(*storage.Token)) {
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, fn)
+	token := s.token
+	s.mu.Unlock()
+
+	if token != nil {
+		fn(token)
+	}
+}
+
+// Token returns the current token, or an error if none has been loaded
+func (s *TokenSource) Token() (*storage.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return nil, fmt.Errorf("no token loaded")
+	}
+	return s.token, nil
+}
+
+// Health returns a snapshot of the source's state
+func (s *TokenSource) Health() Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.health
+	h.Healthy = s.token != nil && !s.token.IsExpired()
+	return h
+}
+
+// Run keeps the token fresh until ctx is done. It fails only if no token is stored.
+func (s *TokenSource) Run(ctx context.Context) error {
+	token, err := s.flow.storage.LoadToken()
+	if err != nil {
+		return fmt.Errorf("no stored token - please run 'mission-control auth login': %w", err)
+	}
+	s.update(token, false)
+
+	for {
+		wait := s.nextWait()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+
+		// Pick up a token refreshed or replaced elsewhere
+		if current, err := s.flow.storage.LoadToken(); err == nil && current.AccessToken != s.current().AccessToken {
+			logging.Info("Stored token changed, using the new token")
+			s.update(current, false)
+		}
+
+		token := s.current()
+		if token.IsPrivateApp() || time.Now().Before(token.ExpiresAt.Add(-s.lead)) {
+			continue
+		}
+
+		logging.Info("Refreshing token ahead of expiry at %s", token.ExpiresAt.Format("15:04:05"))
+		if err := s.flow.RefreshToken(ctx); err != nil {
+			s.failed(err)
+			continue
+		}
+
+		refreshed, err := s.flow.storage.LoadToken()
+		if err != nil {
+			s.failed(fmt.Errorf("failed to reload token: %w", err))
+			continue
+		}
+		s.update(refreshed, true)
+	}
+}
+
+// current returns the loaded token
+func (s *TokenSource) current() *storage.Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// update records a new token and notifies subscribers
+func (s *TokenSource) update(token *storage.Token, refreshed bool) {
+	s.mu.Lock()
+	s.token = token
+	s.health.CredentialType = token.CredentialType()
+	s.health.ExpiresAt = token.ExpiresAt
+	s.health.LastError = ""
+	s.health.ConsecutiveFailures = 0
+	if refreshed {
+		s.health.LastRefresh = time.Now()
+	}
+	subscribers := append([]func(*storage.Token){}, s.subscribers...)
+	s.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(token)
+	}
+}
+
+// failed records a refresh failure
+func (s *TokenSource) failed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health.LastError = err.Error()
+	s.health.ConsecutiveFailures++
+	logging.Error("Token refresh failed (attempt %d): %v", s.health.ConsecutiveFailures, err)
+}
+
+// nextWait returns how long to sleep before the next check and records when the
+// next refresh is due
+func (s *TokenSource) nextWait() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.health.ConsecutiveFailures > 0 {
+		backoff := s.minBackoff << (s.health.ConsecutiveFailures - 1)
+		if backoff > s.maxBackoff || backoff <= 0 {
+			backoff = s.maxBackoff
+		}
+		s.health.NextRefresh = time.Now().Add(backoff)
+		return backoff
+	}
+
+	if s.token.IsPrivateApp() {
+		s.health.NextRefresh = time.Time{}
+		return s.pollInterval
+	}
+
+	due := s.token.ExpiresAt.Add(-s.lead)
+	s.health.NextRefresh = due
+	wait := time.Until(due)
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > s.pollInterval {
+		wait = s.pollInterval
+	}
+	return wait
+}
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/launch01/mission-control/internal/config"
+	"github.com/launch01/mission-control/internal/storage"
+)
+
+func newTestTokenSource(t *testing.T, handler http.HandlerFunc, expiresIn time.Duration) *TokenSource {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	store := storage.NewFileTokenStorage(filepath.Join(t.TempDir(), "token.json"))
+	token := &storage.Token{AccessToken: "access-0", RefreshToken: "refresh-0", ExpiresAt: time.Now().Add(expiresIn)}
+	if err := store.SaveToken(token); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	flow := &AuthFlow{
+		cfg:      &config.Config{HubSpot: config.HubSpotConfig{ClientID: "client"}},
+		provider: &Provider{TokenURL: server.URL},
+		storage:  store,
+	}
+	source := NewTokenSource(flow, 10*time.Minute)
+	source.pollInterval = 10 * time.Millisecond
+	source.minBackoff = 10 * time.Millisecond
+	return source
+}
+
+func TestTokenSourceRefreshesAheadOfExpiry(t *testing.T) {
+	source := newTestTokenSource(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"access-1","refresh_token":"refresh-1","expires_in":1800}`))
+	}, 2*time.Minute)
+
+	tokens := make(chan string, 4)
+	source.Subscribe(func(token *storage.Token) { tokens <- token.AccessToken })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go source.Run(ctx)
+
+	for _, want := range []string{"access-0", "access-1"} {
+		select {
+		case got := <-tokens:
+			if got != want {
+				t.Fatalf("subscriber got %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("subscriber never got %q", want)
+		}
+	}
+
+	health := source.Health()
+	if !health.Healthy || health.LastRefresh.IsZero() || health.ConsecutiveFailures != 0 {
+		t.Errorf("Health() = %+v", health)
+	}
+	if time.Until(health.ExpiresAt) < 20*time.Minute {
+		t.Errorf("ExpiresAt = %v, want the refreshed expiry", health.ExpiresAt)
+	}
+}
+
+func TestTokenSourceBacksOff(t *testing.T) {
+	source := newTestTokenSource(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}, 2*time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go source.Run(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for source.Health().ConsecutiveFailures < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Health() = %+v, want repeated failures", source.Health())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	health := source.Health()
+	if health.LastError == "" {
+		t.Error("LastError should describe the failure")
+	}
+	if !health.Healthy {
+		t.Error("a failing refresh of a still-valid token should stay healthy")
+	}
+	if !health.NextRefresh.After(time.Now().Add(-time.Second)) {
+		t.Errorf("NextRefresh = %v, want the backoff deadline", health.NextRefresh)
+	}
+}
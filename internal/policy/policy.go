@@ -0,0 +1,277 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule actions
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+// Sentinel errors for policy outcomes, usable with errors.Is
+var (
+	// ErrDenied means the policy forbids the call
+	ErrDenied = errors.New("denied by policy")
+	// ErrConfirmationRequired means the policy requires confirmation and none was given
+	ErrConfirmationRequired = errors.New("confirmation required by policy")
+)
+
+// Policy decides which tool calls may leave the process.
+//
+// Every rule whose tools patterns match the call applies: a deny rule rejects the call,
+// argument constraints must all hold, and any matching confirm rule requires confirmation.
+// When Default is "deny", a call must also match at least one allow rule.
+type Policy struct {
+	Default string `yaml:"default"`
+	Rules   []Rule `yaml:"rules"`
+
+	path string
+}
+
+// Rule applies an action and argument constraints to tools matching its patterns
+type Rule struct {
+	// Tools are glob patterns matched against "server/tool" and the bare tool name
+	Tools   []string                 `yaml:"tools"`
+	Action  string                   `yaml:"action"`
+	Reason  string                   `yaml:"reason"`
+	Confirm bool                     `yaml:"confirm"`
+	Args    map[string]ArgConstraint `yaml:"args"`
+}
+
+// ArgConstraint limits the value of a single tool argument
+type ArgConstraint struct {
+	Required bool          `yaml:"required"`
+	Enum     []interface{} `yaml:"enum"`
+	Min      *float64      `yaml:"min"`
+	Max      *float64      `yaml:"max"`
+	MaxItems *int          `yaml:"maxItems"`
+	Pattern  string        `yaml:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// Decision is the outcome of evaluating a call against the policy
+type Decision struct {
+	Allowed bool
+	Confirm bool
+	Reasons []string
+}
+
+// DeniedError reports why the policy rejected a tool call
+type DeniedError struct {
+	Tool    string
+	Reasons []string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("tool %s denied by policy: %s", e.Tool, strings.Join(e.Reasons, "; "))
+}
+
+// Is reports whether target is ErrDenied
+func (e *DeniedError) Is(target error) bool { return target == ErrDenied }
+
+// Load reads and validates a policy file
+func Load(filePath string) (*Policy, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", filePath, err)
+	}
+	p.path = filePath
+
+	return p, nil
+}
+
+// Parse parses and validates a YAML (or JSON) policy document
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+
+	if p.Default == "" {
+		p.Default = ActionAllow
+	}
+	if p.Default != ActionAllow && p.Default != ActionDeny {
+		return nil, fmt.Errorf("default must be %q or %q, got %q", ActionAllow, ActionDeny, p.Default)
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if len(rule.Tools) == 0 {
+			return nil, fmt.Errorf("rule %d: tools is required", i+1)
+		}
+		for _, pattern := range rule.Tools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid tool pattern %q", i+1, pattern)
+			}
+		}
+		if rule.Action != "" && rule.Action != ActionAllow && rule.Action != ActionDeny {
+			return nil, fmt.Errorf("rule %d: action must be %q or %q, got %q", i+1, ActionAllow, ActionDeny, rule.Action)
+		}
+		for name, constraint := range rule.Args {
+			if constraint.Pattern != "" {
+				re, err := regexp.Compile(constraint.Pattern)
+				if err != nil {
+					return nil, fmt.Errorf("rule %d: invalid pattern for %s: %w", i+1, name, err)
+				}
+				constraint.pattern = re
+				rule.Args[name] = constraint
+			}
+		}
+	}
+
+	return &p, nil
+}
+
+// Path returns the file the policy was loaded from
+func (p *Policy) Path() string {
+	return p.path
+}
+
+// Evaluate checks a call to tool, named "server/tool", with the given arguments
+func (p *Policy) Evaluate(tool string, args map[string]interface{}) Decision {
+	decision := Decision{Allowed: true}
+	explicitlyAllowed := false
+
+	for i, rule := range p.Rules {
+		if !rule.matches(tool) {
+			continue
+		}
+
+		switch rule.Action {
+		case ActionDeny:
+			reason := rule.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("matched deny rule %d", i+1)
+			}
+			decision.Allowed = false
+			decision.Reasons = append(decision.Reasons, reason)
+		case ActionAllow:
+			explicitlyAllowed = true
+		}
+
+		for name, constraint := range rule.Args {
+			if violation := constraint.check(name, args); violation != "" {
+				if rule.Reason != "" {
+					violation += " (" + rule.Reason + ")"
+				}
+				decision.Allowed = false
+				decision.Reasons = append(decision.Reasons, violation)
+			}
+		}
+
+		if rule.Confirm {
+			decision.Confirm = true
+		}
+	}
+
+	if p.Default == ActionDeny && !explicitlyAllowed {
+		decision.Allowed = false
+		decision.Reasons = append(decision.Reasons, "no rule allows this tool and the default is deny")
+	}
+
+	return decision
+}
+
+// matches reports whether any of the rule's patterns match the qualified or bare tool name
+func (r Rule) matches(tool string) bool {
+	_, bare, _ := strings.Cut(tool, "/")
+	for _, pattern := range r.Tools {
+		if ok, _ := path.Match(pattern, tool); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, bare); bare != "" && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns a description of how the argument violates the constraint, or ""
+func (c ArgConstraint) check(name string, args map[string]interface{}) string {
+	value, ok := args[name]
+	if !ok || value == nil {
+		if c.Required {
+			return fmt.Sprintf("argument %s is required", name)
+		}
+		return ""
+	}
+
+	if len(c.Enum) > 0 {
+		found := false
+		for _, allowed := range c.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("argument %s must be one of %v, got %v", name, c.Enum, value)
+		}
+	}
+
+	if c.Min != nil || c.Max != nil {
+		n, ok := toFloat(value)
+		if !ok {
+			return fmt.Sprintf("argument %s must be a number, got %v", name, value)
+		}
+		if c.Min != nil && n < *c.Min {
+			return fmt.Sprintf("argument %s must be at least %v, got %v", name, *c.Min, value)
+		}
+		if c.Max != nil && n > *c.Max {
+			return fmt.Sprintf("argument %s must be at most %v, got %v", name, *c.Max, value)
+		}
+	}
+
+	if c.MaxItems != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Sprintf("argument %s must be a list", name)
+		}
+		if len(items) > *c.MaxItems {
+			return fmt.Sprintf("argument %s may have at most %d items, got %d", name, *c.MaxItems, len(items))
+		}
+	}
+
+	if c.pattern != nil {
+		s, ok := value.(string)
+		if !ok || !c.pattern.MatchString(s) {
+			return fmt.Sprintf("argument %s must match %s, got %v", name, c.Pattern, value)
+		}
+	}
+
+	return ""
+}
+
+// toFloat converts JSON and Go numeric values, and numeric strings, to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+const testPolicy = `
+default: allow
+rules:
+  - tools: ["*delete*"]
+    action: deny
+    reason: deletes are not allowed
+  - tools: ["hubspot/search_*"]
+    args:
+      objectType:
+        enum: [contacts, deals]
+      limit:
+        max: 100
+  - tools: ["*batch_create*"]
+    confirm: true
+    args:
+      inputs:
+        maxItems: 2
+`
+
+func TestEvaluate(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		tool        string
+		args        map[string]interface{}
+		wantAllowed bool
+		wantConfirm bool
+	}{
+		{"allowed search", "hubspot/search_objects", map[string]interface{}{"objectType": "deals", "limit": float64(50)}, true, false},
+		{"enum violation", "hubspot/search_objects", map[string]interface{}{"objectType": "tickets"}, false, false},
+		{"max violation", "hubspot/search_objects", map[string]interface{}{"limit": float64(500)}, false, false},
+		{"bare name glob", "billing/delete_invoice", nil, false, false},
+		{"other server unconstrained", "billing/search_invoices", map[string]interface{}{"limit": float64(500)}, true, false},
+		{"batch confirm", "hubspot/batch_create_objects", map[string]interface{}{"inputs": []interface{}{1, 2}}, true, true},
+		{"batch too large", "hubspot/batch_create_objects", map[string]interface{}{"inputs": []interface{}{1, 2, 3}}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := p.Evaluate(tt.tool, tt.args)
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v (reasons: %v)", decision.Allowed, tt.wantAllowed, decision.Reasons)
+			}
+			if decision.Confirm != tt.wantConfirm {
+				t.Errorf("Confirm = %v, want %v", decision.Confirm, tt.wantConfirm)
+			}
+			if !decision.Allowed && len(decision.Reasons) == 0 {
+				t.Error("Denied decision should explain why")
+			}
+		})
+	}
+}
+
+func TestDefaultDeny(t *testing.T) {
+	p, err := Parse([]byte(`
+default: deny
+rules:
+  - tools: ["hubspot/*"]
+    action: allow
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if !p.Evaluate("hubspot/search_contacts", nil).Allowed {
+		t.Error("Explicitly allowed tool should be allowed")
+	}
+	if p.Evaluate("billing/get_invoice", nil).Allowed {
+		t.Error("Tool without an allow rule should be denied")
+	}
+}
+
+func TestParseRejectsInvalidPolicy(t *testing.T) {
+	invalid := []string{
+		`default: maybe`,
+		`rules: [{action: deny}]`,
+		`rules: [{tools: ["x"], action: block}]`,
+		`rules: [{tools: ["x"], args: {name: {pattern: "("}}}]`,
+	}
+
+	for _, doc := range invalid {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Parse(%q) should fail", doc)
+		}
+	}
+}
+
+func TestDeniedError(t *testing.T) {
+	err := error(&DeniedError{Tool: "hubspot/delete_contact", Reasons: []string{"no"}})
+	if !errors.Is(err, ErrDenied) {
+		t.Error("DeniedError should match ErrDenied")
+	}
+}
@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/launch01/mission-control/internal/logging"
+	"github.com/zalando/go-keyring"
+)
+
+// Variables selecting where tokens are stored
+const (
+	// CredentialBackendEnv names the backend: auto (default), keyring, file, env or helper
+	CredentialBackendEnv = "MISSION_CONTROL_CREDENTIAL_BACKEND"
+	// CredentialHelperEnv names the credential helper program used by the helper backend
+	CredentialHelperEnv = "MISSION_CONTROL_CREDENTIAL_HELPER"
+)
+
+// Backend names accepted in MISSION_CONTROL_CREDENTIAL_BACKEND
+const (
+	BackendAuto    = "auto"
+	BackendKeyring = "keyring"
+	BackendFile    = "file"
+	BackendEnv     = "env"
+	BackendHelper  = "helper"
+	BackendMemory  = "memory"
+)
+
+var (
+	// ErrNotFound is returned by a backend holding no token for the key
+	ErrNotFound = errors.New("no token found")
+	// ErrReadOnly is returned when writing to a backend that can't store tokens
+	ErrReadOnly = errors.New("credential backend is read-only")
+)
+
+// Backend stores serialized tokens by key, the profile name
+type Backend interface {
+	// Name returns the backend's name, e.g. "keyring"
+	Name() string
+	// Get returns the stored data, or ErrNotFound
+	Get(key string) ([]byte, error)
+	// Set stores data, replacing any previous value
+	Set(key string, data []byte) error
+	// Delete removes the stored data, or returns ErrNotFound
+	Delete(key string) error
+	// Location describes where the data for key is kept, for user-facing messages
+	Location(key string) string
+}
+
+// SelectBackend returns the backend configured with MISSION_CONTROL_CREDENTIAL_BACKEND.
+// The default uses the OS keyring when available and encrypted files otherwise.
+func SelectBackend() (Backend, error) {
+	name := os.Getenv(CredentialBackendEnv)
+	helper := os.Getenv(CredentialHelperEnv)
+	if name == "" && helper != "" {
+		name = BackendHelper
+	}
+
+	switch name {
+	case "", BackendAuto:
+		if KeyringAvailable() {
+			return NewKeyringBackend(), nil
+		}
+		return defaultFileBackend()
+	case BackendKeyring:
+		if !KeyringAvailable() {
+			return nil, fmt.Errorf("OS keyring is not available - set %s=file to store tokens in encrypted files", CredentialBackendEnv)
+		}
+		return NewKeyringBackend(), nil
+	case BackendFile:
+		return defaultFileBackend()
+	case BackendEnv:
+		return NewEnvBackend(), nil
+	case BackendHelper:
+		if helper == "" {
+			return nil, fmt.Errorf("%s=helper needs %s", CredentialBackendEnv, CredentialHelperEnv)
+		}
+		return NewHelperBackend(helper)
+	}
+	return nil, fmt.Errorf("unknown credential backend %q (use %s, %s, %s, %s or %s)", name, BackendAuto, BackendKeyring, BackendFile, BackendEnv, BackendHelper)
+}
+
+// keyringBackend stores tokens in the OS keyring
+type keyringBackend struct{}
+
+// NewKeyringBackend creates a backend storing tokens in the OS keyring
+func NewKeyringBackend() Backend {
+	return keyringBackend{}
+}
+
+func (keyringBackend) Name() string { return BackendKeyring }
+
+func (keyringBackend) entry(key string) string {
+	return tokenKey + ":" + key
+}
+
+func (b keyringBackend) Get(key string) ([]byte, error) {
+	data, err := keyring.Get(serviceName, b.entry(key))
+	if err == keyring.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token from keyring: %w", err)
+	}
+	return []byte(data), nil
+}
+
+func (b keyringBackend) Set(key string, data []byte) error {
+	if err := keyring.Set(serviceName, b.entry(key), string(data)); err != nil {
+		return fmt.Errorf("failed to save token to keyring: %w", err)
+	}
+	return nil
+}
+
+func (b keyringBackend) Delete(key string) error {
+	err := keyring.Delete(serviceName, b.entry(key))
+	if err == keyring.ErrNotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete token from keyring: %w", err)
+	}
+	return nil
+}
+
+func (b keyringBackend) Location(key string) string {
+	return fmt.Sprintf("OS keyring (service %q, entry %q)", serviceName, b.entry(key))
+}
+
+// fileBackend stores tokens in encrypted files, one <key>.json per key in dir, or
+// every key in file when it is set
+type fileBackend struct {
+	dir  string
+	file string
+}
+
+// NewFileBackend creates a backend storing tokens in encrypted files in dir
+func NewFileBackend(dir string) Backend {
+	return &fileBackend{dir: dir}
+}
+
+// defaultFileBackend returns the file backend for the per-profile token directory
+func defaultFileBackend() (Backend, error) {
+	dir, err := tokensDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileBackend(dir), nil
+}
+
+func (b *fileBackend) Name() string { return BackendFile }
+
+func (b *fileBackend) path(key string) string {
+	if b.file != "" {
+		return b.file
+	}
+	return filepath.Join(b.dir, key+".json")
+}
+
+// backupSuffix names the copy of the previous token kept next to each token file
+const backupSuffix = ".bak"
+
+func (b *fileBackend) Get(key string) ([]byte, error) {
+	path := b.path(key)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	// Files written before encryption existed are re-saved encrypted by
+	// TokenStorage.LoadToken, which can do it under the token lock
+	data, _, err := openToken(raw, filepath.Dir(path))
+	var corrupt *corruptTokenError
+	if errors.As(err, &corrupt) {
+		return b.recover(path, corrupt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// plaintext reports whether the token file was written before encryption existed
+func (b *fileBackend) plaintext(key string) bool {
+	raw, err := os.ReadFile(b.path(key))
+	if err != nil {
+		return false
+	}
+	var env envelope
+	return json.Unmarshal(raw, &env) == nil && env.Format == ""
+}
+
+// recover restores a corrupt token file from its backup, returning the backed up
+// token, or cause if the backup is unusable too. A backup under a different key
+// source is never used: a file that fails under the wrong passphrase looks corrupt,
+// and an older machine-key backup would replace a newer token.
+func (b *fileBackend) recover(path string, cause *corruptTokenError) ([]byte, error) {
+	raw, err := os.ReadFile(path + backupSuffix)
+	if err != nil {
+		return nil, cause
+	}
+	var env envelope
+	if json.Unmarshal(raw, &env) != nil || (cause.keySource != "" && env.KeySource != cause.keySource) {
+		return nil, cause
+	}
+	data, _, err := openToken(raw, filepath.Dir(path))
+	if err != nil {
+		return nil, cause
+	}
+
+	if err := writeFileAtomic(path, raw, 0600); err != nil {
+		logging.Error("Failed to restore token file %s from its backup: %v", path, err)
+	} else {
+		logging.Error("Token file %s was unreadable (%v); restored the previous token from %s", path, cause, path+backupSuffix)
+	}
+	return data, nil
+}
+
+// Set encrypts data and writes it with restricted permissions, replaced atomically
+// so readers never see a half-written token. The previous token is kept as a
+// backup to recover from a corrupt file.
+func (b *fileBackend) Set(key string, data []byte) error {
+	path := b.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Only intact encrypted files are backed up: never a corrupt file over a good
+	// backup, nor a plaintext token
+	if previous, err := os.ReadFile(path); err == nil {
+		var env envelope
+		if json.Unmarshal(previous, &env) == nil && env.Format == envelopeFormat {
+			if err := writeFileAtomic(path+backupSuffix, previous, 0600); err != nil {
+				logging.Error("Failed to back up token file %s: %v", path, err)
+			}
+		}
+	}
+
+	sealed, err := sealToken(data, filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt token: %w", err)
+	}
+	return writeFileAtomic(path, sealed, 0600)
+}
+
+// Delete removes the token file and its backup
+func (b *fileBackend) Delete(key string) error {
+	path := b.path(key)
+	if err := os.Remove(path + backupSuffix); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete token backup: %w", err)
+	}
+
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete token file: %w", err)
+	}
+	return nil
+}
+
+func (b *fileBackend) Location(key string) string {
+	return b.path(key)
+}
+
+// memoryBackend keeps tokens in memory, for tests
+type memoryBackend struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+// NewMemoryBackend creates a backend keeping tokens in memory until the process exits
+func NewMemoryBackend() Backend {
+	return &memoryBackend{data: map[string][]byte{}}
+}
+
+func (b *memoryBackend) Name() string { return BackendMemory }
+
+func (b *memoryBackend) Get(key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (b *memoryBackend) Set(key string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (b *memoryBackend) Delete(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.data[key]; !ok {
+		return ErrNotFound
+	}
+	delete(b.data, key)
+	return nil
+}
+
+func (b *memoryBackend) Location(key string) string {
+	return "memory"
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	store := NewBackendTokenStorage(NewMemoryBackend(), "sandbox")
+
+	if _, err := store.LoadToken(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LoadToken() error = %v, want ErrNotFound", err)
+	}
+
+	token := &Token{AccessToken: "memory-access", RefreshToken: "memory-refresh", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := store.SaveToken(token); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	loaded, err := store.LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if loaded.AccessToken != "memory-access" || loaded.RefreshToken != "memory-refresh" {
+		t.Errorf("LoadToken() = %+v", loaded)
+	}
+
+	if err := store.DeleteToken(); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	if err := store.DeleteToken(); err != nil {
+		t.Errorf("DeleteToken() of a missing token error = %v", err)
+	}
+	if _, err := store.LoadToken(); err == nil {
+		t.Error("LoadToken() should fail after deletion")
+	}
+}
+
+func TestEnvBackend(t *testing.T) {
+	t.Setenv(TokenEnv, "pat-na1-shared")
+	t.Setenv(TokenEnv+"_PROD_EU", `{"access_token":"prod-access","refresh_token":"prod-refresh","expires_at":"2030-01-01T00:00:00Z"}`)
+
+	token, err := NewBackendTokenStorage(NewEnvBackend(), "prod-eu").LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "prod-access" || token.RefreshToken != "prod-refresh" {
+		t.Errorf("LoadToken() = %+v, want the profile's JSON token", token)
+	}
+
+	store := NewBackendTokenStorage(NewEnvBackend(), DefaultProfile)
+	token, err = store.LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "pat-na1-shared" || !token.IsPrivateApp() {
+		t.Errorf("LoadToken() = %+v, want a bare token used as a private app token", token)
+	}
+
+	if err := store.SaveToken(token); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("SaveToken() error = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestHelperBackend(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script needs a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "helper")
+	script := `#!/bin/sh
+store="` + dir + `/store"
+profile=""
+token=""
+while IFS= read -r line && [ -n "$line" ]; do
+	case "$line" in
+	profile=*) profile="${line#profile=}" ;;
+	token=*) token="${line#token=}" ;;
+	esac
+done
+case "$1" in
+get) [ -f "$store.$profile" ] && printf 'token=%s\n' "$(cat "$store.$profile")" ;;
+store) printf '%s' "$token" > "$store.$profile" ;;
+erase) rm -f "$store.$profile" ;;
+esac
+exit 0
+`
+	if err := os.WriteFile(helper, []byte(script), 0700); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	t.Setenv(CredentialBackendEnv, "")
+	t.Setenv(CredentialHelperEnv, helper)
+	backend, err := SelectBackend()
+	if err != nil {
+		t.Fatalf("SelectBackend() error = %v", err)
+	}
+	if backend.Name() != BackendHelper {
+		t.Fatalf("SelectBackend() = %s, want the helper backend when a helper is configured", backend.Name())
+	}
+
+	store := NewBackendTokenStorage(backend, "sandbox")
+	if _, err := store.LoadToken(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LoadToken() error = %v, want ErrNotFound", err)
+	}
+	if err := store.SaveToken(&Token{AccessToken: "helper-access", RefreshToken: "helper-refresh"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	token, err := store.LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "helper-access" || token.RefreshToken != "helper-refresh" {
+		t.Errorf("LoadToken() = %+v", token)
+	}
+	if err := store.DeleteToken(); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "store.sandbox")); !os.IsNotExist(err) {
+		t.Error("helper should have erased the token")
+	}
+}
+
+func TestSelectBackend(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(CredentialHelperEnv, "")
+
+	t.Setenv(CredentialBackendEnv, BackendFile)
+	backend, err := SelectBackend()
+	if err != nil || backend.Name() != BackendFile {
+		t.Errorf("SelectBackend() = %v, %v, want the file backend", backend, err)
+	}
+
+	t.Setenv(CredentialBackendEnv, BackendHelper)
+	if _, err := SelectBackend(); err == nil || !strings.Contains(err.Error(), CredentialHelperEnv) {
+		t.Errorf("SelectBackend() error = %v, want a missing helper error", err)
+	}
+
+	t.Setenv(CredentialBackendEnv, "vault")
+	if _, err := SelectBackend(); err == nil {
+		t.Error("SelectBackend() should reject an unknown backend")
+	}
+
+	backend, _ = NewHelperBackend("pass --store work")
+	if got := backend.(*helperBackend).command; got[0] != "mission-control-credential-pass" || len(got) != 3 {
+		t.Errorf("NewHelperBackend() command = %v", got)
+	}
+}
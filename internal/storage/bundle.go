@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BundlePassphraseEnv names the variable holding the passphrase of credential bundles
+const BundlePassphraseEnv = "MISSION_CONTROL_BUNDLE_PASSPHRASE"
+
+const (
+	bundleFormat  = "mission-control-bundle"
+	bundleVersion = 1
+
+	// minBundlePassphrase is the shortest passphrase accepted for a new bundle
+	minBundlePassphrase = 8
+)
+
+// Bundle is a profile's token and settings exported to set up another machine
+type Bundle struct {
+	Version   int       `json:"version"`
+	Profile   string    `json:"profile"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the bundle stops being importable; zero means never
+	ExpiresAt time.Time `json:"expires_at"`
+	// Settings are the profile's saved settings, opaque to storage
+	Settings json.RawMessage `json:"settings,omitempty"`
+	Token    *Token          `json:"token"`
+}
+
+// SealBundle encrypts a bundle with a key derived from passphrase. The AES-GCM
+// authentication tag doubles as the bundle's integrity check.
+func SealBundle(b *Bundle, passphrase string) ([]byte, error) {
+	if len(passphrase) < minBundlePassphrase {
+		return nil, fmt.Errorf("bundle passphrase must be at least %d characters", minBundlePassphrase)
+	}
+	if b.Token == nil {
+		return nil, fmt.Errorf("bundle has no token")
+	}
+
+	b.Version = bundleVersion
+	b.Token.Version = TokenSchemaVersion
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	return seal(bundleFormat, data, passphrase, "")
+}
+
+// OpenBundle decrypts and verifies a bundle and rejects it once it has expired
+func OpenBundle(data []byte, passphrase string) (*Bundle, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Format != bundleFormat {
+		return nil, errors.New("not a mission-control credential bundle")
+	}
+	if env.Version != envelopeVersion || env.KeySource != keySourcePassphrase {
+		return nil, fmt.Errorf("unsupported bundle version %d", env.Version)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("bundle is encrypted with a passphrase - set %s", BundlePassphraseEnv)
+	}
+
+	plain, err := open(&env, passphrase, "")
+	if err != nil {
+		return nil, errors.New("failed to decrypt bundle - wrong passphrase, or the file was modified")
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(plain, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	if b.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	if !b.ExpiresAt.IsZero() && time.Now().After(b.ExpiresAt) {
+		return nil, fmt.Errorf("bundle expired at %s - export a new one", b.ExpiresAt.Format("2006-01-02 15:04:05"))
+	}
+	if b.Token == nil || b.Token.AccessToken == "" {
+		return nil, fmt.Errorf("bundle has no token")
+	}
+	if err := ValidateProfileName(b.Profile); err != nil {
+		return nil, err
+	}
+	if _, err := migrateToken(b.Token); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	bundle := &Bundle{
+		Profile:   "sandbox",
+		CreatedAt: time.Now(),
+		Settings:  json.RawMessage(`{"client_id":"client-123"}`),
+		Token:     &Token{AccessToken: "bundle-access", RefreshToken: "bundle-refresh", HubID: 42},
+	}
+
+	data, err := SealBundle(bundle, "correct horse")
+	if err != nil {
+		t.Fatalf("SealBundle() error = %v", err)
+	}
+	if bytes.Contains(data, []byte("bundle-")) || bytes.Contains(data, []byte("client-123")) {
+		t.Fatal("bundle contains the token or settings in plaintext")
+	}
+
+	opened, err := OpenBundle(data, "correct horse")
+	if err != nil {
+		t.Fatalf("OpenBundle() error = %v", err)
+	}
+	if opened.Profile != "sandbox" || opened.Token.RefreshToken != "bundle-refresh" || opened.Token.HubID != 42 {
+		t.Errorf("OpenBundle() = %+v", opened)
+	}
+	if string(opened.Settings) != `{"client_id":"client-123"}` {
+		t.Errorf("Settings = %s", opened.Settings)
+	}
+
+	if _, err := OpenBundle(data, "wrong horse"); err == nil {
+		t.Error("OpenBundle() should fail with the wrong passphrase")
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	env.Ciphertext[0] ^= 1
+	tampered, _ := json.Marshal(env)
+	if _, err := OpenBundle(tampered, "correct horse"); err == nil {
+		t.Error("OpenBundle() should reject a modified bundle")
+	}
+
+	if _, err := SealBundle(bundle, "short"); err == nil {
+		t.Error("SealBundle() should reject a short passphrase")
+	}
+}
+
+func TestExpiredBundle(t *testing.T) {
+	bundle := &Bundle{
+		Profile:   DefaultProfile,
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(-time.Hour),
+		Token:     &Token{AccessToken: "old-access"},
+	}
+	data, err := SealBundle(bundle, "correct horse")
+	if err != nil {
+		t.Fatalf("SealBundle() error = %v", err)
+	}
+	if _, err := OpenBundle(data, "correct horse"); err == nil {
+		t.Error("OpenBundle() should reject an expired bundle")
+	}
+}
+
+func TestTamperedArgon2Params(t *testing.T) {
+	data, err := SealBundle(&Bundle{Profile: DefaultProfile, Token: &Token{AccessToken: "a"}}, "correct horse")
+	if err != nil {
+		t.Fatalf("SealBundle() error = %v", err)
+	}
+
+	for _, params := range []argon2Params{
+		{Time: 0, Memory: 64 * 1024, Threads: 4},
+		{Time: 1, Memory: 64 * 1024, Threads: 0},
+		{Time: 11, Memory: 64 * 1024, Threads: 4},
+		{Time: 1, Memory: 1<<32 - 1, Threads: 4},
+	} {
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		p := params
+		env.Argon2 = &p
+		tampered, _ := json.Marshal(env)
+		// Must fail before deriving a key, without panicking or allocating the memory
+		if _, err := OpenBundle(tampered, "correct horse"); err == nil {
+			t.Errorf("OpenBundle() should reject argon2 parameters %+v", params)
+		}
+		if _, err := deriveKey(&env, "correct horse", "", false); err == nil {
+			t.Errorf("deriveKey() should reject argon2 parameters %+v", params)
+		}
+	}
+}
+
+func TestTruncatedNonce(t *testing.T) {
+	data, err := SealBundle(&Bundle{Profile: DefaultProfile, Token: &Token{AccessToken: "a"}}, "correct horse")
+	if err != nil {
+		t.Fatalf("SealBundle() error = %v", err)
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	env.Nonce = env.Nonce[:4]
+	tampered, _ := json.Marshal(env)
+	if _, err := OpenBundle(tampered, "correct horse"); err == nil {
+		t.Error("OpenBundle() should reject a truncated nonce")
+	}
+}
@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+// PassphraseEnv names the variable holding the passphrase that encrypts token files.
+// Without it, token files are encrypted with a key file bound to this machine.
+const PassphraseEnv = "MISSION_CONTROL_PASSPHRASE"
+
+const (
+	envelopeFormat  = "mission-control-token"
+	envelopeVersion = 1
+
+	keySourcePassphrase = "passphrase"
+	keySourceKeyFile    = "keyfile"
+
+	kdfArgon2id   = "argon2id"
+	kdfHKDFSHA256 = "hkdf-sha256"
+
+	// machineKeyFile holds the random key used when no passphrase is set
+	machineKeyFile = ".machine.key"
+)
+
+// argon2Params are the Argon2id cost parameters recorded in each envelope
+type argon2Params struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` // KiB
+	Threads uint8  `json:"threads"`
+}
+
+var defaultArgon2Params = argon2Params{Time: 1, Memory: 64 * 1024, Threads: 4}
+
+// validate bounds parameters read from an unauthenticated header, which could
+// otherwise make argon2 panic or allocate gigabytes
+func (p *argon2Params) validate() error {
+	if p.Time < 1 || p.Time > 10 || p.Memory > 1024*1024 || p.Threads < 1 {
+		return fmt.Errorf("unsupported argon2 parameters time=%d memory=%d threads=%d", p.Time, p.Memory, p.Threads)
+	}
+	return nil
+}
+
+// envelope is the on-disk format of an encrypted token file. The header fields
+// are authenticated along with the ciphertext.
+type envelope struct {
+	Format     string        `json:"format"`
+	Version    int           `json:"version"`
+	KeySource  string        `json:"key_source"`
+	KDF        string        `json:"kdf"`
+	Argon2     *argon2Params `json:"argon2,omitempty"`
+	Salt       []byte        `json:"salt"`
+	Nonce      []byte        `json:"nonce"`
+	Ciphertext []byte        `json:"ciphertext"`
+}
+
+// additionalData binds the header to the ciphertext
+func (e *envelope) additionalData() []byte {
+	return []byte(fmt.Sprintf("%s/v%d/%s/%s", e.Format, e.Version, e.KeySource, e.KDF))
+}
+
+// sealToken encrypts plaintext with AES-256-GCM under a key derived from the
+// passphrase, or from the machine key file in dir when no passphrase is set
+func sealToken(plaintext []byte, dir string) ([]byte, error) {
+	return seal(envelopeFormat, plaintext, os.Getenv(PassphraseEnv), dir)
+}
+
+// seal encrypts plaintext into an envelope of the given format
+func seal(format string, plaintext []byte, passphrase, dir string) ([]byte, error) {
+	env := &envelope{Format: format, Version: envelopeVersion}
+	env.Salt = make([]byte, 16)
+	if _, err := rand.Read(env.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	if passphrase != "" {
+		env.KeySource = keySourcePassphrase
+		env.KDF = kdfArgon2id
+		params := defaultArgon2Params
+		env.Argon2 = &params
+	} else {
+		env.KeySource = keySourceKeyFile
+		env.KDF = kdfHKDFSHA256
+	}
+
+	key, err := deriveKey(env, passphrase, dir, true)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	env.Ciphertext = gcm.Seal(nil, env.Nonce, plaintext, env.additionalData())
+
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// corruptTokenError reports a token file that is damaged, as opposed to one that
+// can't be read with the current settings; only damaged files are restored from
+// their backup
+type corruptTokenError struct {
+	// keySource is the damaged file's, empty when its header is unreadable
+	keySource string
+	err       error
+}
+
+func (e *corruptTokenError) Error() string { return e.err.Error() }
+func (e *corruptTokenError) Unwrap() error { return e.err }
+
+// openToken decrypts a token file. A file written before encryption existed is
+// returned as is, with plaintext set so the caller can re-save it encrypted.
+func openToken(data []byte, dir string) (token []byte, plaintext bool, err error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, false, &corruptTokenError{err: fmt.Errorf("failed to parse token file: %w", err)}
+	}
+	if env.Format == "" {
+		return data, true, nil
+	}
+	if env.Format != envelopeFormat || env.Version != envelopeVersion {
+		return nil, false, fmt.Errorf("unsupported token file format %s version %d", env.Format, env.Version)
+	}
+
+	passphrase := os.Getenv(PassphraseEnv)
+	if env.KeySource == keySourcePassphrase && passphrase == "" {
+		return nil, false, fmt.Errorf("token file is encrypted with a passphrase - set %s", PassphraseEnv)
+	}
+	key, err := deriveKey(&env, passphrase, dir, false)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decrypt token file: %w", err)
+	}
+	token, err = openWithKey(&env, key)
+	if err != nil {
+		if errors.Is(err, errInvalidNonce) {
+			err = fmt.Errorf("token file is corrupt: %w", err)
+		} else if env.KeySource == keySourcePassphrase {
+			err = fmt.Errorf("failed to decrypt token file - wrong %s?", PassphraseEnv)
+		} else {
+			err = fmt.Errorf("failed to decrypt token file - was it copied from another machine?")
+		}
+		return nil, false, &corruptTokenError{keySource: env.KeySource, err: err}
+	}
+	return token, false, nil
+}
+
+var errInvalidNonce = errors.New("invalid nonce length")
+
+// open decrypts an envelope, authenticating its header and ciphertext
+func open(env *envelope, passphrase, dir string) ([]byte, error) {
+	key, err := deriveKey(env, passphrase, dir, false)
+	if err != nil {
+		return nil, err
+	}
+	return openWithKey(env, key)
+}
+
+func openWithKey(env *envelope, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	// The nonce comes from the unauthenticated header, and gcm.Open panics on a bad length
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: %d bytes", errInvalidNonce, len(env.Nonce))
+	}
+	return gcm.Open(nil, env.Nonce, env.Ciphertext, env.additionalData())
+}
+
+// deriveKey derives the envelope's AES key. A missing machine key is created only
+// when create is set, i.e. when writing.
+func deriveKey(env *envelope, passphrase, dir string, create bool) ([]byte, error) {
+	switch env.KeySource {
+	case keySourcePassphrase:
+		if passphrase == "" {
+			return nil, fmt.Errorf("no passphrase given")
+		}
+		if env.KDF != kdfArgon2id || env.Argon2 == nil {
+			return nil, fmt.Errorf("unsupported key derivation %q", env.KDF)
+		}
+		p := env.Argon2
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		return argon2.IDKey([]byte(passphrase), env.Salt, p.Time, p.Memory, p.Threads, 32), nil
+
+	case keySourceKeyFile:
+		if env.KDF != kdfHKDFSHA256 {
+			return nil, fmt.Errorf("unsupported key derivation %q", env.KDF)
+		}
+		secret, err := machineKey(dir, create)
+		if err != nil {
+			return nil, err
+		}
+		key := make([]byte, 32)
+		info := []byte(envelopeFormat + "/" + machineID())
+		if _, err := io.ReadFull(hkdf.New(sha256.New, secret, env.Salt, info), key); err != nil {
+			return nil, fmt.Errorf("failed to derive key: %w", err)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key source %q", env.KeySource)
+}
+
+// machineKey reads the random key file in dir, creating it if allowed
+func machineKey(dir string, create bool) ([]byte, error) {
+	path := filepath.Join(dir, machineKeyFile)
+	key, err := readMachineKey(path)
+	if err == nil || !errors.Is(err, os.ErrNotExist) || !create {
+		return key, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	// The key is written in full before it appears under its name, and linking
+	// fails if another process created the key meanwhile, in which case theirs is used
+	tmp, err := writeTempFile(path, key, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+	defer os.Remove(tmp)
+	if err := os.Link(tmp, path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return readMachineKey(path)
+		}
+		return nil, fmt.Errorf("failed to create key file: %w", err)
+	}
+	syncDir(dir)
+	return key, nil
+}
+
+// readMachineKey reads the key file, retrying a short read in case a version that
+// wrote the key in place is still writing it
+func readMachineKey(path string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+		}
+		if len(key) >= 32 {
+			return key, nil
+		}
+		if attempt == 4 {
+			return nil, fmt.Errorf("key file %s is corrupt", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// machineID returns an identifier of this machine, so a copied key file and token
+// don't decrypt elsewhere; empty where none is available
+func machineID() string {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if id, err := os.ReadFile(path); err == nil {
+			return strings.TrimSpace(string(id))
+		}
+	}
+	return ""
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEncryptedFileStorage(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "token.json")
+	store := NewFileTokenStorage(path)
+
+	token := &Token{AccessToken: "secret-access", RefreshToken: "secret-refresh", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := store.SaveToken(token); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if bytes.Contains(raw, []byte("secret-")) {
+		t.Fatal("token file contains the token in plaintext")
+	}
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil || env.Format != envelopeFormat || env.Version != envelopeVersion || env.KeySource != keySourceKeyFile {
+		t.Fatalf("unexpected envelope %+v (%v)", env, err)
+	}
+	if info, err := os.Stat(filepath.Join(filepath.Dir(path), machineKeyFile)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("machine key file missing or not 0600: %v", err)
+	}
+
+	loaded, err := store.LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if loaded.AccessToken != "secret-access" || loaded.RefreshToken != "secret-refresh" {
+		t.Errorf("LoadToken() = %+v", loaded)
+	}
+
+	// Tampering with the authenticated header must fail decryption
+	env.KeySource = keySourcePassphrase
+	env.KDF = kdfArgon2id
+	env.Argon2 = &defaultArgon2Params
+	tampered, _ := json.Marshal(env)
+	t.Setenv(PassphraseEnv, "guess")
+	if _, _, err := openToken(tampered, filepath.Dir(path)); err == nil {
+		t.Error("openToken() should reject a tampered envelope")
+	}
+}
+
+func TestPassphraseEncryption(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	sealed, err := sealToken([]byte(`{"access_token":"a"}`), dir)
+	if err != nil {
+		t.Fatalf("sealToken() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, machineKeyFile)); !os.IsNotExist(err) {
+		t.Error("passphrase mode should not create a machine key")
+	}
+
+	plain, wasPlaintext, err := openToken(sealed, dir)
+	if err != nil || wasPlaintext || string(plain) != `{"access_token":"a"}` {
+		t.Fatalf("openToken() = %q, %v, %v", plain, wasPlaintext, err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, _, err := openToken(sealed, dir); err == nil || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Errorf("openToken() with the wrong passphrase error = %v", err)
+	}
+
+	t.Setenv(PassphraseEnv, "")
+	if _, _, err := openToken(sealed, dir); err == nil {
+		t.Error("openToken() without the passphrase should fail")
+	}
+}
+
+func TestPlaintextFileMigration(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := os.WriteFile(path, []byte(`{"access_token":"old-access","refresh_token":"old-refresh"}`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	token, err := NewFileTokenStorage(path).LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "old-access" {
+		t.Errorf("AccessToken = %q", token.AccessToken)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte("old-access")) {
+		t.Error("plaintext token file should be encrypted after it is read")
+	}
+	if token, err := NewFileTokenStorage(path).LoadToken(); err != nil || token.RefreshToken != "old-refresh" {
+		t.Errorf("LoadToken() after migration = %+v, %v", token, err)
+	}
+}
+
+func TestPlaintextFileNotEncryptedDuringRefresh(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "token.json")
+	plaintext := `{"access_token":"old-access","refresh_token":"old-refresh","version":2}`
+	if err := os.WriteFile(path, []byte(plaintext), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// Another process holds the lock while refreshing
+	refresher := NewFileTokenStorage(path)
+	unlock, err := refresher.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if _, err := NewFileTokenStorage(path).LoadToken(); err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if raw, _ := os.ReadFile(path); string(raw) != plaintext {
+		t.Errorf("token file was re-saved without the lock: %s", raw)
+	}
+	if err := refresher.SaveToken(&Token{AccessToken: "new-access", RefreshToken: "new-refresh"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	unlock()
+
+	token, err := NewFileTokenStorage(path).LoadToken()
+	if err != nil || token.RefreshToken != "new-refresh" {
+		t.Errorf("LoadToken() = %+v, %v; want the refreshed token", token, err)
+	}
+}
+
+func TestConcurrentMachineKeyCreation(t *testing.T) {
+	dir := t.TempDir()
+	keys := make([][]byte, 8)
+	var wg sync.WaitGroup
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key, err := machineKey(dir, true)
+			if err != nil {
+				t.Errorf("machineKey() error = %v", err)
+			}
+			keys[i] = key
+		}(i)
+	}
+	wg.Wait()
+
+	for _, key := range keys[1:] {
+		if !bytes.Equal(key, keys[0]) {
+			t.Fatal("concurrent callers got different machine keys")
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the key file", len(entries))
+	}
+}
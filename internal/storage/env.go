@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TokenEnv holds the token read by the env backend: the JSON of a stored token, or a
+// bare access token that is used as is and never refreshed. TokenEnv_<PROFILE> takes
+// precedence for a profile.
+const TokenEnv = "MISSION_CONTROL_TOKEN"
+
+// envBackend reads tokens from environment variables, e.g. CI secrets
+type envBackend struct{}
+
+// NewEnvBackend creates a read-only backend reading tokens from environment variables
+func NewEnvBackend() Backend {
+	return envBackend{}
+}
+
+func (envBackend) Name() string { return BackendEnv }
+
+// vars returns the variables consulted for key, most specific first
+func (envBackend) vars(key string) []string {
+	suffix := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+	return []string{TokenEnv + "_" + suffix, TokenEnv}
+}
+
+func (b envBackend) Get(key string) ([]byte, error) {
+	for _, name := range b.vars(key) {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			continue
+		}
+		if strings.HasPrefix(value, "{") {
+			if !json.Valid([]byte(value)) {
+				return nil, fmt.Errorf("%s is not a valid token", name)
+			}
+			return []byte(value), nil
+		}
+		return json.Marshal(&Token{AccessToken: value, Type: TokenTypePrivateApp})
+	}
+	return nil, ErrNotFound
+}
+
+func (envBackend) Set(key string, data []byte) error {
+	return fmt.Errorf("%w: set %s instead", ErrReadOnly, TokenEnv)
+}
+
+func (envBackend) Delete(key string) error {
+	return fmt.Errorf("%w: unset %s instead", ErrReadOnly, TokenEnv)
+}
+
+func (b envBackend) Location(key string) string {
+	return "environment variable " + strings.Join(b.vars(key), " or ")
+}
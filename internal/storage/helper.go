@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// helperPrefix is prepended to helper names that aren't paths, as git does with
+// git-credential-<name>
+const helperPrefix = "mission-control-credential-"
+
+// helperTimeout bounds each run of a credential helper
+const helperTimeout = 30 * time.Second
+
+// helperBackend delegates storage to an external credential helper program. Like a
+// git credential helper, it is run as "<helper> get|store|erase" with key=value lines
+// on stdin:
+//
+//	service=mission-control
+//	profile=<profile>
+//	token=<token JSON>     (store only)
+//
+// get prints a token=<token JSON> line, or nothing when no token is stored.
+type helperBackend struct {
+	command []string
+}
+
+// NewHelperBackend creates a backend running the given credential helper command. A
+// bare name such as "pass" runs mission-control-credential-pass from the PATH.
+func NewHelperBackend(helper string) (Backend, error) {
+	command := strings.Fields(helper)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("empty credential helper")
+	}
+	if !strings.ContainsRune(command[0], '/') && !strings.ContainsRune(command[0], filepath.Separator) {
+		command[0] = helperPrefix + command[0]
+	}
+	return &helperBackend{command: command}, nil
+}
+
+func (b *helperBackend) Name() string { return BackendHelper }
+
+// run runs the helper with the given operation and returns its output
+func (b *helperBackend) run(op, key string, token []byte) ([]byte, error) {
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "service=%s\nprofile=%s\n", serviceName, key)
+	if token != nil {
+		fmt.Fprintf(&input, "token=%s\n", token)
+	}
+	input.WriteString("\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), helperTimeout)
+	defer cancel()
+
+	args := append(append([]string{}, b.command[1:]...), op)
+	cmd := exec.CommandContext(ctx, b.command[0], args...)
+	cmd.Stdin = &input
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("credential helper %s %s failed: %w: %s", b.command[0], op, err, msg)
+		}
+		return nil, fmt.Errorf("credential helper %s %s failed: %w", b.command[0], op, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (b *helperBackend) Get(key string) ([]byte, error) {
+	out, err := b.run("get", key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "token="); ok && value != "" {
+			return []byte(value), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (b *helperBackend) Set(key string, data []byte) error {
+	if bytes.ContainsAny(data, "\r\n") {
+		return fmt.Errorf("token data for a credential helper must be a single line")
+	}
+	_, err := b.run("store", key, data)
+	return err
+}
+
+// Delete asks the helper to erase the token. Helpers don't report whether a token
+// existed, so a successful erase always counts as a removal.
+func (b *helperBackend) Delete(key string) error {
+	_, err := b.run("erase", key, nil)
+	return err
+}
+
+func (b *helperBackend) Location(key string) string {
+	return fmt.Sprintf("credential helper %s (profile %s)", b.command[0], key)
+}
@@ -0,0 +1,46 @@
+package storage
+
+import "fmt"
+
+// TokenSchemaVersion is the stored token format written by this build. Tokens
+// stored without a version are version 1.
+//
+//	1: access token, refresh token, expiry and type
+//	2: adds granted scopes, hub ID, client ID, MCP server URL, obtained and
+//	   refreshed timestamps and the refresh count
+const TokenSchemaVersion = 2
+
+// tokenMigrations upgrade a token from the version it is keyed by to the next one
+var tokenMigrations = map[int]func(*Token){
+	1: func(t *Token) {
+		t.Type = t.CredentialType()
+		// Cached introspection results are the best local record of the grant
+		if t.Info != nil {
+			t.HubID = t.Info.HubID
+			t.Scopes = t.Info.Scopes
+		}
+	},
+}
+
+// migrateToken upgrades a token read in an older format to TokenSchemaVersion,
+// reporting whether it changed
+func migrateToken(t *Token) (bool, error) {
+	if t.Version == 0 {
+		t.Version = 1
+	}
+	if t.Version > TokenSchemaVersion {
+		return false, fmt.Errorf("token was stored in format %d by a newer mission-control (this version reads up to %d) - please upgrade", t.Version, TokenSchemaVersion)
+	}
+
+	migrated := false
+	for t.Version < TokenSchemaVersion {
+		migrate, ok := tokenMigrations[t.Version]
+		if !ok {
+			return false, fmt.Errorf("no migration for token format %d", t.Version)
+		}
+		migrate(t)
+		t.Version++
+		migrated = true
+	}
+	return migrated, nil
+}
@@ -1,130 +1,534 @@
 package storage
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/launch01/mission-control/internal/lockfile"
+	"github.com/launch01/mission-control/internal/logging"
 	"github.com/zalando/go-keyring"
 )
 
 const (
 	serviceName = "mission-control"
 	tokenKey    = "hubspot-token"
+
+	// DefaultProfile is the profile used when none is selected; tokens stored
+	// before profiles existed are migrated into it
+	DefaultProfile = "default"
+)
+
+// Credential types stored in Token.Type
+const (
+	// TokenTypeOAuth is an OAuth access token with a refresh token; the default when unset
+	TokenTypeOAuth = "oauth"
+	// TokenTypePrivateApp is a non-expiring HubSpot private app access token
+	TokenTypePrivateApp = "private_app"
 )
 
-// Token represents an OAuth token
+// Token represents an OAuth token or a private app access token, with what is known
+// locally about it
 type Token struct {
+	// Version is the format the token was stored in; see TokenSchemaVersion
+	Version      int       `json:"version,omitempty"`
 	AccessToken  string    `json:"access_token"`
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresAt    time.Time `json:"expires_at"`
+	Type         string    `json:"type,omitempty"`
+
+	// Scopes are the scopes granted to the token, or requested when the grant is unknown
+	Scopes []string `json:"scopes,omitempty"`
+	// HubID is the HubSpot portal the token belongs to, when known
+	HubID int64 `json:"hub_id,omitempty"`
+	// ClientID is the OAuth client that obtained the token
+	ClientID string `json:"client_id,omitempty"`
+	// ServerURL is the MCP server the token was obtained for
+	ServerURL string `json:"server_url,omitempty"`
+
+	ObtainedAt   time.Time `json:"obtained_at"`
+	RefreshedAt  time.Time `json:"refreshed_at"`
+	RefreshCount int       `json:"refresh_count"`
+
+	// Info caches the token's metadata from HubSpot; it is replaced when the token is introspected again
+	Info *TokenInfo `json:"info,omitempty"`
+}
+
+// TokenInfo describes the portal, user and app a token belongs to and the scopes it grants
+type TokenInfo struct {
+	HubID     int64     `json:"hub_id"`
+	HubDomain string    `json:"hub_domain,omitempty"`
+	User      string    `json:"user,omitempty"`
+	UserID    int64     `json:"user_id,omitempty"`
+	AppID     int64     `json:"app_id"`
+	Scopes    []string  `json:"scopes"`
+	FetchedAt time.Time `json:"fetched_at"`
 }
 
-// TokenStorage handles secure storage of OAuth tokens
+// TokenStorage handles secure storage of the token for one profile
 type TokenStorage struct {
-	useKeyring bool
-	filePath   string
+	backend Backend
+	profile string
 }
 
-// NewTokenStorage creates a new token storage
+// NewTokenStorage creates a new token storage for the default profile
 func NewTokenStorage() (*TokenStorage, error) {
-	// Try to use keyring first
-	if isKeyringAvailable() {
-		return &TokenStorage{useKeyring: true}, nil
+	return NewProfileTokenStorage(DefaultProfile)
+}
+
+// NewProfileTokenStorage creates a token storage keyed by profile name, in the
+// backend selected with MISSION_CONTROL_CREDENTIAL_BACKEND.
+// A token stored before profiles existed is migrated into the default profile.
+func NewProfileTokenStorage(profile string) (*TokenStorage, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if err := ValidateProfileName(profile); err != nil {
+		return nil, err
 	}
 
-	// Fall back to file storage with warning
-	home, err := os.UserHomeDir()
+	backend, err := SelectBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &TokenStorage{backend: backend, profile: profile}
+	if profile == DefaultProfile {
+		switch backend.(type) {
+		case keyringBackend:
+			s.migrateLegacyKeyring()
+		case *fileBackend:
+			s.migrateLegacyFile()
+		}
+	}
+	return s, nil
+}
+
+// NewFileTokenStorage creates a token storage kept in a single file, bypassing the keyring
+func NewFileTokenStorage(filePath string) *TokenStorage {
+	return &TokenStorage{backend: &fileBackend{file: filePath}, profile: DefaultProfile}
+}
+
+// NewBackendTokenStorage creates a token storage for a profile in the given backend
+func NewBackendTokenStorage(backend Backend, profile string) *TokenStorage {
+	return &TokenStorage{backend: backend, profile: profile}
+}
+
+// ValidateProfileName rejects names that can't be used as keyring keys or file names
+func ValidateProfileName(name string) error {
+	if name == "" || len(name) > 64 || name[0] == '.' {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return fmt.Errorf("invalid profile name %q: use letters, digits, '-', '_' and '.'", name)
+		}
+	}
+	return nil
+}
+
+// Profile returns the profile this storage holds the token for
+func (s *TokenStorage) Profile() string {
+	if s.profile == "" {
+		return DefaultProfile
+	}
+	return s.profile
+}
+
+// Backend returns the backend the token is stored in
+func (s *TokenStorage) Backend() Backend {
+	return s.backend
+}
+
+// LockPath returns the lock file that serializes token refreshes for this storage
+// across processes
+func (s *TokenStorage) LockPath() (string, error) {
+	if b, ok := s.backend.(*fileBackend); ok {
+		return b.path(s.Profile()) + ".lock", nil
+	}
+
+	dir, err := tokensDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, s.Profile()+".lock"), nil
+}
+
+// tokenLocks serializes read-modify-write cycles within this process, keyed by lock file path
+var tokenLocks sync.Map
+
+// Lock takes the advisory lock that serializes read-modify-write cycles of the token,
+// such as refreshes, within this process and across processes. The returned function
+// releases it.
+func (s *TokenStorage) Lock(ctx context.Context) (func(), error) {
+	return s.lock(ctx, true)
+}
+
+// tryLock takes the lock only if it is free
+func (s *TokenStorage) tryLock() (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return s.lock(ctx, false)
+}
+
+func (s *TokenStorage) lock(ctx context.Context, wait bool) (func(), error) {
+	path, err := s.LockPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+		return nil, err
 	}
 
-	configDir := filepath.Join(home, ".config", "mission-control")
-	if err := os.MkdirAll(configDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	mu, _ := tokenLocks.LoadOrStore(path, &sync.Mutex{})
+	if !wait {
+		if !mu.(*sync.Mutex).TryLock() {
+			return nil, fmt.Errorf("token %s is locked", s.Profile())
+		}
+	} else {
+		mu.(*sync.Mutex).Lock()
 	}
 
-	filePath := filepath.Join(configDir, "token.json")
-	return &TokenStorage{
-		useKeyring: false,
-		filePath:   filePath,
+	// A canceled ctx makes Acquire try once
+	lock, err := lockfile.Acquire(ctx, path)
+	if err != nil {
+		mu.(*sync.Mutex).Unlock()
+		return nil, err
+	}
+	return func() {
+		lock.Release()
+		mu.(*sync.Mutex).Unlock()
 	}, nil
 }
 
-// SaveToken saves the token securely
+// Update loads the token, applies fn and saves the result while holding the lock, so
+// a concurrent refresh is never overwritten with stale data
+func (s *TokenStorage) Update(ctx context.Context, fn func(*Token) error) error {
+	unlock, err := s.Lock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to lock token: %w", err)
+	}
+	defer unlock()
+
+	token, err := s.LoadToken()
+	if err != nil {
+		return err
+	}
+	if err := fn(token); err != nil {
+		return err
+	}
+	return s.SaveToken(token)
+}
+
+// SaveToken saves the token securely in the current format
 func (s *TokenStorage) SaveToken(token *Token) error {
+	token.Version = TokenSchemaVersion
 	data, err := json.Marshal(token)
 	if err != nil {
 		return fmt.Errorf("failed to marshal token: %w", err)
 	}
+	return s.backend.Set(s.Profile(), data)
+}
 
-	if s.useKeyring {
-		return keyring.Set(serviceName, tokenKey, string(data))
+// writeFileAtomic writes data to a temporary file next to path, flushes it to disk
+// and renames it into place, so a crash leaves either the old or the new file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := writeTempFile(path, data, perm)
+	if err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
 	}
+	defer os.Remove(tmp)
 
-	// File storage with restricted permissions
-	if err := os.WriteFile(s.filePath, data, 0600); err != nil {
+	if err := os.Rename(tmp, path); err != nil {
 		return fmt.Errorf("failed to write token file: %w", err)
 	}
-
+	syncDir(filepath.Dir(path))
 	return nil
 }
 
+// writeTempFile writes data to a new temporary file next to path and flushes it
+// to disk, returning its name
+func writeTempFile(path string, data []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	err = tmp.Chmod(perm)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// syncDir flushes a rename in dir to disk; platforms that can't sync directories skip it
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
 // LoadToken loads the token from secure storage
 func (s *TokenStorage) LoadToken() (*Token, error) {
-	var data string
-	var err error
-
-	if s.useKeyring {
-		data, err = keyring.Get(serviceName, tokenKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get token from keyring: %w", err)
-		}
-	} else {
-		bytes, err := os.ReadFile(s.filePath)
-		if err != nil {
-			if os.IsNotExist(err) {
-				return nil, fmt.Errorf("no token found - please run 'mission-control auth login' first")
-			}
-			return nil, fmt.Errorf("failed to read token file: %w", err)
+	data, err := s.backend.Get(s.Profile())
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("%w - please run 'mission-control auth login' first", err)
 		}
-		data = string(bytes)
+		return nil, err
 	}
 
 	var token Token
-	if err := json.Unmarshal([]byte(data), &token); err != nil {
+	if err := json.Unmarshal(data, &token); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal token: %w", err)
 	}
 
+	migrated, err := migrateToken(&token)
+	if err != nil {
+		return nil, err
+	}
+	// A plaintext file is re-saved encrypted the same way as a migrated token
+	plaintext := s.storedPlaintext()
+	if (migrated || plaintext) && s.saveMigrated(data, &token) && plaintext {
+		logging.Info("Encrypted plaintext token file %s", s.Location())
+	}
+
 	return &token, nil
 }
 
-// DeleteToken deletes the stored token
-func (s *TokenStorage) DeleteToken() error {
-	if s.useKeyring {
-		return keyring.Delete(serviceName, tokenKey)
+// saveMigrated saves a token migrated on read if the lock is free and the stored token
+// is still the one read, so a concurrent refresh is never overwritten, and reports
+// whether it did. It is best effort: otherwise, as with a read-only backend, the token
+// is migrated again on the next read.
+func (s *TokenStorage) saveMigrated(read []byte, token *Token) bool {
+	unlock, err := s.tryLock()
+	if err != nil {
+		logging.Debug("Not saving migrated token: %v", err)
+		return false
 	}
+	defer unlock()
 
-	if err := os.Remove(s.filePath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete token file: %w", err)
+	if current, err := s.backend.Get(s.Profile()); err != nil || !bytes.Equal(current, read) {
+		return false
+	}
+	if err := s.SaveToken(token); err != nil {
+		logging.Debug("Failed to save migrated token: %v", err)
+		return false
 	}
+	return true
+}
 
+// storedPlaintext reports whether the token is in a file written before encryption existed
+func (s *TokenStorage) storedPlaintext() bool {
+	b, ok := s.backend.(*fileBackend)
+	return ok && b.plaintext(s.Profile())
+}
+
+// DeleteToken deletes the stored token; deleting a missing token is not an error
+func (s *TokenStorage) DeleteToken() error {
+	if err := s.backend.Delete(s.Profile()); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
 	return nil
 }
 
-// IsExpired checks if the token is expired
+// Location describes where the token is stored, for user-facing messages
+func (s *TokenStorage) Location() string {
+	return s.backend.Location(s.Profile())
+}
+
+// DeleteAll removes the tokens of the given profiles, and any token stored before
+// profiles existed, from the keyring, the fallback files and the configured credential
+// helper, so tokens left behind by an earlier storage mode are wiped too.
+// It returns the locations a token was actually removed from.
+func DeleteAll(profiles []string) ([]string, error) {
+	var removed []string
+
+	files, err := defaultFileBackend()
+	if err != nil {
+		return nil, err
+	}
+	backends := []Backend{files}
+	if KeyringAvailable() {
+		backends = append(backends, NewKeyringBackend())
+	}
+	if selected, err := SelectBackend(); err == nil && selected.Name() == BackendHelper {
+		backends = append(backends, selected)
+	}
+
+	for _, backend := range backends {
+		for _, profile := range profiles {
+			err := backend.Delete(profile)
+			if err == nil {
+				removed = append(removed, backend.Location(profile))
+			} else if !errors.Is(err, ErrNotFound) {
+				return removed, err
+			}
+		}
+	}
+
+	// Tokens stored before profiles existed
+	if KeyringAvailable() {
+		err := keyring.Delete(serviceName, tokenKey)
+		if err == nil {
+			removed = append(removed, fmt.Sprintf("OS keyring (service %q, entry %q)", serviceName, tokenKey))
+		} else if err != keyring.ErrNotFound {
+			return removed, fmt.Errorf("failed to delete token from keyring: %w", err)
+		}
+	}
+	legacyFile, err := defaultTokenFilePath()
+	if err != nil {
+		return removed, err
+	}
+	if err := os.Remove(legacyFile); err == nil {
+		removed = append(removed, legacyFile)
+	} else if !os.IsNotExist(err) {
+		return removed, fmt.Errorf("failed to delete token file: %w", err)
+	}
+
+	return removed, nil
+}
+
+// ListFileProfiles returns the profiles that have a token in the fallback files
+func ListFileProfiles() ([]string, error) {
+	dir, err := tokensDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read token directory: %w", err)
+	}
+
+	var profiles []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && filepath.Ext(name) == ".json" {
+			profiles = append(profiles, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	return profiles, nil
+}
+
+// migrateLegacyKeyring moves a token stored under the pre-profile keyring entry into the default profile
+func (s *TokenStorage) migrateLegacyKeyring() {
+	data, err := keyring.Get(serviceName, tokenKey)
+	if err != nil {
+		return
+	}
+
+	if _, err := s.backend.Get(s.Profile()); errors.Is(err, ErrNotFound) {
+		if err := s.backend.Set(s.Profile(), []byte(data)); err != nil {
+			logging.Error("Failed to migrate stored token to the %s profile: %v", DefaultProfile, err)
+			return
+		}
+	}
+
+	keyring.Delete(serviceName, tokenKey)
+	logging.Info("Migrated stored token to the %s profile", DefaultProfile)
+}
+
+// migrateLegacyFile moves the pre-profile token file into the default profile
+func (s *TokenStorage) migrateLegacyFile() {
+	legacy, err := defaultTokenFilePath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return
+	}
+
+	path := s.Location()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			logging.Error("Failed to migrate stored token to the %s profile: %v", DefaultProfile, err)
+			return
+		}
+		if err := os.Rename(legacy, path); err != nil {
+			logging.Error("Failed to migrate stored token to the %s profile: %v", DefaultProfile, err)
+			return
+		}
+		logging.Info("Migrated stored token to the %s profile", DefaultProfile)
+	}
+}
+
+// CredentialType returns the token's type, treating tokens stored before types existed as OAuth
+func (t *Token) CredentialType() string {
+	if t.Type == "" {
+		return TokenTypeOAuth
+	}
+	return t.Type
+}
+
+// IsPrivateApp reports whether the token is a private app access token
+func (t *Token) IsPrivateApp() bool {
+	return t.Type == TokenTypePrivateApp
+}
+
+// IsExpired checks if the token is expired; private app tokens never expire
 func (t *Token) IsExpired() bool {
+	if t.IsPrivateApp() {
+		return false
+	}
 	return time.Now().After(t.ExpiresAt)
 }
 
 // IsExpiringSoon checks if the token will expire within the given duration
 func (t *Token) IsExpiringSoon(d time.Duration) bool {
+	if t.IsPrivateApp() {
+		return false
+	}
 	return time.Now().Add(d).After(t.ExpiresAt)
 }
 
-func isKeyringAvailable() bool {
+// defaultTokenFilePath returns the fallback token file used before profiles existed
+func defaultTokenFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "mission-control", "token.json"), nil
+}
+
+// tokensDir returns the directory holding one fallback token file per profile
+func tokensDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "mission-control", "tokens"), nil
+}
+
+// profileTokenFilePath returns the fallback token file for a profile
+func profileTokenFilePath(profile string) (string, error) {
+	dir, err := tokensDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, profile+".json"), nil
+}
+
+// KeyringAvailable reports whether the OS keyring can store tokens
+func KeyringAvailable() bool {
 	// Test if keyring is available by trying to set/get/delete a test value
 	testKey := "test-availability"
 	err := keyring.Set(serviceName, testKey, "test")
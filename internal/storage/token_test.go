@@ -1,8 +1,13 @@
 package storage
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -37,14 +42,36 @@ func TestTokenExpiry(t *testing.T) {
 	}
 }
 
+func TestPrivateAppTokenNeverExpires(t *testing.T) {
+	token := &Token{
+		AccessToken: "pat-na1-test",
+		Type:        TokenTypePrivateApp,
+	}
+
+	if token.IsExpired() {
+		t.Error("Private app token should not be expired")
+	}
+
+	if token.IsExpiringSoon(24 * time.Hour) {
+		t.Error("Private app token should not be expiring soon")
+	}
+
+	if token.CredentialType() != TokenTypePrivateApp {
+		t.Errorf("CredentialType() = %s, want %s", token.CredentialType(), TokenTypePrivateApp)
+	}
+
+	legacy := &Token{AccessToken: "test-access"}
+	if legacy.CredentialType() != TokenTypeOAuth {
+		t.Errorf("Untyped token CredentialType() = %s, want %s", legacy.CredentialType(), TokenTypeOAuth)
+	}
+}
+
 func TestFileStorage(t *testing.T) {
 	// Create temp directory
 	tmpDir := t.TempDir()
 
-	storage := &TokenStorage{
-		useKeyring: false,
-		filePath:   filepath.Join(tmpDir, "token.json"),
-	}
+	path := filepath.Join(tmpDir, "token.json")
+	storage := NewFileTokenStorage(path)
 
 	token := &Token{
 		AccessToken:  "test-access-token",
@@ -88,10 +115,8 @@ func TestFileStorage(t *testing.T) {
 func TestFilePermissions(t *testing.T) {
 	tmpDir := t.TempDir()
 
-	storage := &TokenStorage{
-		useKeyring: false,
-		filePath:   filepath.Join(tmpDir, "token.json"),
-	}
+	path := filepath.Join(tmpDir, "token.json")
+	storage := NewFileTokenStorage(path)
 
 	token := &Token{
 		AccessToken:  "test-access",
@@ -105,7 +130,7 @@ func TestFilePermissions(t *testing.T) {
 	}
 
 	// Check file permissions
-	info, err := os.Stat(storage.filePath)
+	info, err := os.Stat(path)
 	if err != nil {
 		t.Fatalf("Stat() error = %v", err)
 	}
@@ -115,3 +140,265 @@ func TestFilePermissions(t *testing.T) {
 		t.Errorf("File permissions = %o, want 0600", mode)
 	}
 }
+
+func TestProfileFileMigration(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	legacy, err := defaultTokenFilePath()
+	if err != nil {
+		t.Fatalf("defaultTokenFilePath() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(legacy), 0700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(legacy, []byte(`{"access_token":"legacy-access"}`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	defaultPath, _ := profileTokenFilePath(DefaultProfile)
+	if err := os.MkdirAll(filepath.Dir(defaultPath), 0700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	storage := &TokenStorage{backend: NewFileBackend(filepath.Dir(defaultPath)), profile: DefaultProfile}
+	storage.migrateLegacyFile()
+
+	token, err := storage.LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "legacy-access" {
+		t.Errorf("AccessToken = %q, want legacy-access", token.AccessToken)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Error("Legacy token file should be removed after migration")
+	}
+
+	sandboxPath, _ := profileTokenFilePath("sandbox")
+	sandbox := &TokenStorage{backend: NewFileBackend(filepath.Dir(sandboxPath)), profile: "sandbox"}
+	if err := sandbox.SaveToken(&Token{AccessToken: "sandbox-access"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	profiles, err := ListFileProfiles()
+	if err != nil {
+		t.Fatalf("ListFileProfiles() error = %v", err)
+	}
+	if len(profiles) != 2 || profiles[0] != DefaultProfile || profiles[1] != "sandbox" {
+		t.Errorf("ListFileProfiles() = %v, want [default sandbox]", profiles)
+	}
+}
+
+func TestValidateProfileName(t *testing.T) {
+	for _, name := range []string{"default", "sandbox-2", "prod_eu", "portal.123"} {
+		if err := ValidateProfileName(name); err != nil {
+			t.Errorf("ValidateProfileName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../prod", ".hidden", "a/b", "with space"} {
+		if err := ValidateProfileName(name); err == nil {
+			t.Errorf("ValidateProfileName(%q) should fail", name)
+		}
+	}
+}
+
+func TestTokenMigration(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	backend := NewMemoryBackend()
+	store := NewBackendTokenStorage(backend, DefaultProfile)
+	legacy := `{"access_token":"legacy-access","refresh_token":"legacy-refresh","expires_at":"2030-01-01T00:00:00Z","info":{"hub_id":42,"app_id":7,"scopes":["crm.objects.contacts.read"]}}`
+	if err := backend.Set(DefaultProfile, []byte(legacy)); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	token, err := store.LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.Version != TokenSchemaVersion || token.Type != TokenTypeOAuth || token.HubID != 42 {
+		t.Errorf("LoadToken() = %+v, want a migrated token", token)
+	}
+	if len(token.Scopes) != 1 || token.Scopes[0] != "crm.objects.contacts.read" {
+		t.Errorf("Scopes = %v, want the cached scopes", token.Scopes)
+	}
+
+	data, _ := backend.Get(DefaultProfile)
+	if !strings.Contains(string(data), `"version":2`) {
+		t.Errorf("migrated token was not saved: %s", data)
+	}
+
+	if err := backend.Set(DefaultProfile, []byte(`{"version":99,"access_token":"future"}`)); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := store.LoadToken(); err == nil {
+		t.Error("LoadToken() should reject a token from a newer format")
+	}
+}
+
+func TestMigrationDoesNotOverwriteRefresh(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	backend := NewMemoryBackend()
+	legacy := `{"access_token":"legacy-access","refresh_token":"legacy-refresh"}`
+	if err := backend.Set(DefaultProfile, []byte(legacy)); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// Another process holds the lock while refreshing
+	refresher := NewBackendTokenStorage(backend, DefaultProfile)
+	unlock, err := refresher.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	token, err := NewBackendTokenStorage(backend, DefaultProfile).LoadToken()
+	if err != nil || token.Version != TokenSchemaVersion {
+		t.Fatalf("LoadToken() = %+v, %v", token, err)
+	}
+	if data, _ := backend.Get(DefaultProfile); string(data) != legacy {
+		t.Errorf("migrated token was saved without the lock: %s", data)
+	}
+
+	if err := refresher.SaveToken(&Token{AccessToken: "new-access", RefreshToken: "new-refresh"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	unlock()
+
+	token, err = NewBackendTokenStorage(backend, DefaultProfile).LoadToken()
+	if err != nil || token.RefreshToken != "new-refresh" {
+		t.Errorf("LoadToken() = %+v, %v; want the refreshed token", token, err)
+	}
+}
+
+func TestCorruptFileRecovery(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "token.json")
+	store := NewFileTokenStorage(path)
+
+	if err := store.SaveToken(&Token{AccessToken: "first-access"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	if err := store.SaveToken(&Token{AccessToken: "second-access"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	// A torn write leaves a truncated primary file
+	if err := os.WriteFile(path, []byte(`{"format":"mission-con`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	token, err := store.LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "first-access" {
+		t.Errorf("AccessToken = %q, want the backed up token", token.AccessToken)
+	}
+	if _, err := NewFileTokenStorage(path).LoadToken(); err != nil {
+		t.Errorf("primary file should be restored from the backup: %v", err)
+	}
+
+	if err := store.DeleteToken(); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	if _, err := os.Stat(path + backupSuffix); !os.IsNotExist(err) {
+		t.Error("DeleteToken() should remove the backup too")
+	}
+}
+
+func TestTruncatedNonceRecovery(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "token.json")
+	store := NewFileTokenStorage(path)
+	for _, access := range []string{"first-access", "second-access"} {
+		if err := store.SaveToken(&Token{AccessToken: access}); err != nil {
+			t.Fatalf("SaveToken() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	env.Nonce = env.Nonce[:4]
+	data, _ = json.Marshal(env)
+	if _, _, err := openToken(data, filepath.Dir(path)); !errors.As(err, new(*corruptTokenError)) {
+		t.Fatalf("openToken() error = %v, want a corrupt token error", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	token, err := store.LoadToken()
+	if err != nil || token.AccessToken != "first-access" {
+		t.Errorf("LoadToken() = %+v, %v; want the backed up token", token, err)
+	}
+}
+
+func TestNoRecoveryWithoutPassphrase(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "token.json")
+	store := NewFileTokenStorage(path)
+	if err := store.SaveToken(&Token{AccessToken: "machine-key-access"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	// The next refresh happens with a passphrase set, leaving a machine-key backup
+	t.Setenv(PassphraseEnv, "correct horse")
+	if err := store.SaveToken(&Token{AccessToken: "passphrase-access"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	current, _ := os.ReadFile(path)
+
+	t.Setenv(PassphraseEnv, "")
+	if _, err := store.LoadToken(); err == nil || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Errorf("LoadToken() without the passphrase error = %v", err)
+	}
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := store.LoadToken(); err == nil {
+		t.Error("LoadToken() with the wrong passphrase should fail")
+	}
+	if raw, _ := os.ReadFile(path); string(raw) != string(current) {
+		t.Fatal("token file was replaced by its backup")
+	}
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	if token, err := store.LoadToken(); err != nil || token.AccessToken != "passphrase-access" {
+		t.Errorf("LoadToken() = %+v, %v", token, err)
+	}
+}
+
+func TestUpdateSerializesWrites(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := NewFileTokenStorage(path).SaveToken(&Token{AccessToken: "access"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Separate storages, as in separate processes
+			err := NewFileTokenStorage(path).Update(context.Background(), func(token *Token) error {
+				token.RefreshCount++
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	token, err := NewFileTokenStorage(path).LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.RefreshCount != 8 {
+		t.Errorf("RefreshCount = %d, want 8 - updates were lost", token.RefreshCount)
+	}
+}
@@ -95,8 +95,8 @@ echo "======================================"
 echo ""
 echo "Next steps:"
 echo ""
-echo "1. Load environment variables:"
-echo "   source .env"
+echo "1. Run mission-control from this directory - it loads .env automatically"
+echo "   (or: source .env)"
 echo ""
 echo "2. Make sure the HubSpot MCP server is running:"
 echo "   npx @hubspot/mcp-server"